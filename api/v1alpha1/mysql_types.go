@@ -38,6 +38,93 @@ type MySQLSpec struct {
 
 	// AdminPassword is MySQL password to connect target MySQL cluster.
 	AdminPassword Secret `json:"adminPassword"`
+
+	//+kubebuilder:default=Delete
+
+	// DeletionPolicy is the default DeletionPolicy of MySQLUser and MySQLDB in this cluster.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ResyncInterval is the default interval to check grants of MySQLUser for drift.
+	// Drift is not checked periodically if not set.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+
+	//+kubebuilder:default=Correct
+
+	// DriftPolicy is the default DriftPolicy of MySQLUser in this cluster.
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Flavor is the SQL flavor of the cluster, used to validate privileges of MySQLUser
+	// and to create the table of schema migrations. Privileges valid in any flavor are
+	// accepted, and the flavor is detected for schema migrations, if not set.
+	Flavor Flavor `json:"flavor,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=StarRocks;Doris
+type Flavor string
+
+const (
+	FlavorStarRocks Flavor = "StarRocks"
+	FlavorDoris     Flavor = "Doris"
+)
+
+// DriftPolicy decides what to do when grants in the cluster drift from the spec.
+// +kubebuilder:validation:Enum=Correct;Report
+type DriftPolicy string
+
+const (
+	// Report the drift and restore the grants in the spec.
+	DriftPolicyCorrect DriftPolicy = "Correct"
+	// Only report the drift.
+	DriftPolicyReport DriftPolicy = "Report"
+)
+
+// DeletionPolicy decides what happens to the user or database in the cluster
+// when the corresponding object is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type DeletionPolicy string
+
+const (
+	// Drop the user or database.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// Keep the user or database and detach it from the operator.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// Keep the user or database without even looking up the MySQL,
+	// so the object can be deleted after the MySQL is gone.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// AdoptionPolicy decides what to do when the user or database already exists
+// in the cluster before the operator creates it.
+// +kubebuilder:validation:Enum=Adopt;FailIfExists;AdoptReadOnly
+type AdoptionPolicy string
+
+const (
+	// Manage the existing user or database.
+	AdoptionPolicyAdopt AdoptionPolicy = "Adopt"
+	// Don't touch the existing user or database and report NotReady.
+	AdoptionPolicyFailIfExists AdoptionPolicy = "FailIfExists"
+	// Track the existing user or database without changing it.
+	AdoptionPolicyAdoptReadOnly AdoptionPolicy = "AdoptReadOnly"
+)
+
+// Origin records whether the user or database was created or adopted by the operator.
+// Only created ones are dropped when the object is deleted.
+type Origin string
+
+const (
+	OriginCreated Origin = "Created"
+	OriginAdopted Origin = "Adopted"
+)
+
+// resolveDeletionPolicy returns policy if set, otherwise the default of the MySQL.
+func resolveDeletionPolicy(policy DeletionPolicy, mysql *MySQL) DeletionPolicy {
+	if policy != "" {
+		return policy
+	}
+	if mysql != nil && mysql.Spec.DeletionPolicy != "" {
+		return mysql.Spec.DeletionPolicy
+	}
+	return DeletionPolicyDelete
 }
 
 // MySQLStatus defines the observed state of MySQL
@@ -0,0 +1,111 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MySQLGrantSpec defines the desired state of MySQLGrant
+// +kubebuilder:validation:XValidation:rule="has(self.userRef) != has(self.role)",message="Exactly one of userRef and role is required"
+// +kubebuilder:validation:XValidation:rule="!has(self.role) || has(self.clusterName)",message="clusterName is required for role"
+type MySQLGrantSpec struct {
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="userRef is immutable"
+
+	// MySQLUser to grant the privileges to. The grants are merged into the grants of the MySQLUser.
+	UserRef *MySQLUserReference `json:"userRef,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Role is immutable"
+
+	// Role to grant the privileges to. The role must exist in the cluster.
+	Role string `json:"role,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Cluster name is immutable"
+
+	// Cluster name of the role, which decides the destination
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Grants to the user or role
+	Grants []Grant `json:"grants"`
+}
+
+// MySQLUserReference is a reference to a MySQLUser, possibly in another namespace
+type MySQLUserReference struct {
+	// Name of the MySQLUser
+	Name string `json:"name"`
+
+	// Namespace of the MySQLUser. Default to the namespace of the MySQLGrant.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// MySQLGrantStatus defines the observed state of MySQLGrant
+type MySQLGrantStatus struct {
+	// The phase of the grants
+	Phase string `json:"phase,omitempty"`
+
+	// The reason for the current phase
+	Reason string `json:"reason,omitempty"`
+
+	// Grants this MySQLGrant contributed to the role. Revoked on deletion
+	// unless another MySQLGrant grants them to the same role.
+	AppliedGrants []Grant `json:"appliedGrants,omitempty"`
+
+	// The generation of the spec whose grants were last applied
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="User",type="string",JSONPath=".spec.userRef.name",description="The MySQLUser to grant the privileges to"
+//+kubebuilder:printcolumn:name="Role",type="string",JSONPath=".spec.role",description="The role to grant the privileges to"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The phase of MySQLGrant"
+//+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.reason",description="The reason for the current phase of this MySQLGrant"
+
+// MySQLGrant is the Schema for the mysqlgrants API
+type MySQLGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MySQLGrantSpec   `json:"spec,omitempty"`
+	Status MySQLGrantStatus `json:"status,omitempty"`
+}
+
+// GetUserRef returns the namespace and name of the referenced MySQLUser
+func (m MySQLGrant) GetUserRef() (namespace, name string) {
+	if m.Spec.UserRef == nil {
+		return "", ""
+	}
+	namespace = m.Spec.UserRef.Namespace
+	if namespace == "" {
+		namespace = m.Namespace
+	}
+	return namespace, m.Spec.UserRef.Name
+}
+
+//+kubebuilder:object:root=true
+
+// MySQLGrantList contains a list of MySQLGrant
+type MySQLGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MySQLGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MySQLGrant{}, &MySQLGrantList{})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// placeholderRegexp matches a placeholder such as {{database}}
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+
+// MySQLGrantTemplateSpec defines the desired state of MySQLGrantTemplate
+type MySQLGrantTemplateSpec struct {
+	// Grants with placeholders such as {{database}} in the catalog, database,
+	// name and columns of the object, which are replaced with the parameters
+	// given by the MySQLUser
+	Grants []Grant `json:"grants"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// MySQLGrantTemplate is the Schema for the mysqlgranttemplates API
+type MySQLGrantTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MySQLGrantTemplateSpec `json:"spec,omitempty"`
+}
+
+// Expand returns the grants with the placeholders replaced with the parameters.
+// It returns an error listing every placeholder that has no parameter.
+func (m MySQLGrantTemplate) Expand(parameters map[string]string) ([]Grant, error) {
+	var missing []string
+	seen := map[string]bool{}
+	expand := func(s string) string {
+		return placeholderRegexp.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := placeholderRegexp.FindStringSubmatch(placeholder)[1]
+			value, ok := parameters[name]
+			if !ok && !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+
+	grants := make([]Grant, 0, len(m.Spec.Grants))
+	for _, grant := range m.Spec.Grants {
+		grant.Object.Catalog = expand(grant.Object.Catalog)
+		grant.Object.Database = expand(grant.Object.Database)
+		grant.Object.Name = expand(grant.Object.Name)
+		columns := make([]string, 0, len(grant.Columns))
+		for _, column := range grant.Columns {
+			columns = append(columns, expand(column))
+		}
+		if len(columns) > 0 {
+			grant.Columns = columns
+		}
+		grants = append(grants, grant)
+	}
+	if len(missing) > 0 {
+		quoted := make([]string, 0, len(missing))
+		for _, name := range missing {
+			quoted = append(quoted, strconv.Quote(name))
+		}
+		return nil, fmt.Errorf("parameters %s of MySQLGrantTemplate %s are not given", strings.Join(quoted, ", "), m.Name)
+	}
+	return grants, nil
+}
+
+//+kubebuilder:object:root=true
+
+// MySQLGrantTemplateList contains a list of MySQLGrantTemplate
+type MySQLGrantTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MySQLGrantTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MySQLGrantTemplate{}, &MySQLGrantTemplateList{})
+}
@@ -18,6 +18,9 @@ package v1alpha1
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -27,14 +30,86 @@ type SecretRef struct {
 	Key  string `json:"key"`
 }
 
+// ObjectKind is the kind of object on which privileges are granted
+// +kubebuilder:validation:Enum=SYSTEM;CATALOG;DATABASE;TABLE;VIEW;MATERIALIZED VIEW;FUNCTION;RESOURCE;RESOURCE GROUP;STORAGE VOLUME;WORKLOAD GROUP
+type ObjectKind string
+
+const (
+	ObjectKindSystem           ObjectKind = "SYSTEM"
+	ObjectKindCatalog          ObjectKind = "CATALOG"
+	ObjectKindDatabase         ObjectKind = "DATABASE"
+	ObjectKindTable            ObjectKind = "TABLE"
+	ObjectKindView             ObjectKind = "VIEW"
+	ObjectKindMaterializedView ObjectKind = "MATERIALIZED VIEW"
+	ObjectKindFunction         ObjectKind = "FUNCTION"
+	ObjectKindResource         ObjectKind = "RESOURCE"
+	ObjectKindResourceGroup    ObjectKind = "RESOURCE GROUP"
+	ObjectKindStorageVolume    ObjectKind = "STORAGE VOLUME"
+	ObjectKindWorkloadGroup    ObjectKind = "WORKLOAD GROUP"
+)
+
+// ObjectNameAll as Name or Database stands for all objects, e.g.
+// ALL TABLES IN DATABASE db1 is a TABLE with Database db1 and Name "*".
+const ObjectNameAll = "*"
+
+// ObjectRef references the object on which privileges are granted.
+// Name is the name of the object itself, e.g. the database name for DATABASE,
+// and Database is the database containing a TABLE, VIEW, MATERIALIZED VIEW or FUNCTION.
+type ObjectRef struct {
+
+	// Kind of the object
+	Kind ObjectKind `json:"kind"`
+
+	// Catalog containing a DATABASE, TABLE, VIEW, MATERIALIZED VIEW or FUNCTION,
+	// e.g. an external Hive or Iceberg catalog. Default to the internal catalog.
+	Catalog string `json:"catalog,omitempty"`
+
+	// Database containing the object, or "*" for all databases
+	Database string `json:"database,omitempty"`
+
+	// Name of the object, or "*" for all objects of the kind. Not used for SYSTEM.
+	Name string `json:"name,omitempty"`
+}
+
+// String returns a readable form of the object, e.g. "TABLE db1.tbl1"
+func (o ObjectRef) String() string {
+	parts := []string{}
+	for _, part := range []string{o.Catalog, o.Database, o.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return string(o.Kind)
+	}
+	return fmt.Sprintf("%s %s", o.Kind, strings.Join(parts, "."))
+}
+
 // Grant defines the privileges and the resource for a MySQL user
 type Grant struct {
 
 	// Privileges to grant to the user
 	Privileges []string `json:"privileges"`
 
-	// Target on which the privileges are applied
-	Target string `json:"target"`
+	// Object on which the privileges are applied. It replaces target of earlier
+	// versions: a user stored with target is not reconciled until it is rewritten.
+	Object ObjectRef `json:"object"`
+
+	// Columns to which the privileges are limited, e.g. SELECT(col1,col2) ON TABLE
+	Columns []string `json:"columns,omitempty"`
+
+	// GrantOption allows the user to grant the privileges to others.
+	// WITH GRANT OPTION for StarRocks and GRANT_PRIV for Doris.
+	GrantOption bool `json:"grantOption,omitempty"`
+}
+
+// GrantTemplateReference is a reference to a MySQLGrantTemplate with the parameters to expand it
+type GrantTemplateReference struct {
+	// Name of the MySQLGrantTemplate
+	Name string `json:"name"`
+
+	// Values of the placeholders in the template, e.g. database: db1 for {{database}}
+	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
 // MySQLUserSpec defines the desired state of MySQLUser
@@ -64,6 +139,30 @@ type MySQLUserSpec struct {
 
 	// Grants of database user
 	Grants []Grant `json:"grants,omitempty"`
+
+	// MySQLGrantTemplates to expand into grants of the user
+	GrantTemplates []GrantTemplateReference `json:"grantTemplates,omitempty"`
+
+	// Other namespaces whose MySQLGrants may grant privileges to the user, or "*" for any namespace.
+	// MySQLGrants in the namespace of the user are always allowed.
+	AllowedGrantNamespaces []string `json:"allowedGrantNamespaces,omitempty"`
+
+	// What to do with the user when this object is deleted. Default to the MySQL's deletionPolicy.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// +kubebuilder:default=Adopt
+
+	// What to do if the user already exists before the operator creates it
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// Interval to check grants for drift. Default to the MySQL's resyncInterval.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+
+	// What to do when grants drift from the spec. Default to the MySQL's driftPolicy.
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Only plan the statements for the user and its grants without executing them
+	PlanOnly bool `json:"planOnly,omitempty"`
 }
 
 // MySQLUserStatus defines the observed state of MySQLUser
@@ -81,6 +180,24 @@ type MySQLUserStatus struct {
 
 	// true if user is created
 	UserCreated bool `json:"userCreated,omitempty"`
+
+	// Created if the user is created by the operator, Adopted if it existed before
+	Origin Origin `json:"origin,omitempty"`
+
+	// The generation of the spec whose grants were last applied
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Statements that would be executed in plan-only mode, with passwords redacted
+	Plan []string `json:"plan,omitempty"`
+
+	// Statements executed by the last update of grants, including the ones to roll back a failure
+	AppliedStatements []string `json:"appliedStatements,omitempty"`
+
+	// MySQLGrants whose grants were last applied together with the spec, as namespace/name/generation
+	ObservedMySQLGrants []string `json:"observedMySQLGrants,omitempty"`
+
+	// MySQLGrantTemplates whose grants were last applied together with the spec, as name/generation
+	ObservedGrantTemplates []string `json:"observedGrantTemplates,omitempty"`
 }
 
 func (m *MySQLUser) GetConditions() []metav1.Condition {
@@ -94,6 +211,7 @@ func (m *MySQLUser) SetConditions(conditions []metav1.Condition) {
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="MySQLUser",type="boolean",JSONPath=".status.userCreated",description="true if user is created"
+//+kubebuilder:printcolumn:name="Origin",type="string",JSONPath=".status.origin",description="Created or Adopted"
 //+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The phase of this MySQLUser"
 //+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.reason",description="The reason for the current phase of this MySQLUser"
 
@@ -110,6 +228,45 @@ func (u MySQLUser) GetUserIdentity() string {
 	return fmt.Sprintf("'%s'@'%s'", u.Spec.Username, u.Spec.Host)
 }
 
+// IsReadOnly returns true if the user is adopted with AdoptReadOnly and must not be changed.
+func (u MySQLUser) IsReadOnly() bool {
+	return u.Status.Origin == OriginAdopted && u.Spec.AdoptionPolicy == AdoptionPolicyAdoptReadOnly
+}
+
+// AllowsGrantsFrom returns true if MySQLGrants in the namespace may grant privileges to the user.
+func (u MySQLUser) AllowsGrantsFrom(namespace string) bool {
+	return namespace == u.Namespace || slices.Contains(u.Spec.AllowedGrantNamespaces, namespace) ||
+		slices.Contains(u.Spec.AllowedGrantNamespaces, "*")
+}
+
+// GetDeletionPolicy returns the DeletionPolicy of the user, falling back to the given MySQL.
+func (u MySQLUser) GetDeletionPolicy(mysql *MySQL) DeletionPolicy {
+	return resolveDeletionPolicy(u.Spec.DeletionPolicy, mysql)
+}
+
+// GetResyncInterval returns the interval to check grants for drift, falling back to the given MySQL.
+// Zero means no periodic check.
+func (u MySQLUser) GetResyncInterval(mysql *MySQL) time.Duration {
+	if u.Spec.ResyncInterval != nil {
+		return u.Spec.ResyncInterval.Duration
+	}
+	if mysql != nil && mysql.Spec.ResyncInterval != nil {
+		return mysql.Spec.ResyncInterval.Duration
+	}
+	return 0
+}
+
+// GetDriftPolicy returns the DriftPolicy of the user, falling back to the given MySQL.
+func (u MySQLUser) GetDriftPolicy(mysql *MySQL) DriftPolicy {
+	if u.Spec.DriftPolicy != "" {
+		return u.Spec.DriftPolicy
+	}
+	if mysql != nil && mysql.Spec.DriftPolicy != "" {
+		return mysql.Spec.DriftPolicy
+	}
+	return DriftPolicyCorrect
+}
+
 //+kubebuilder:object:root=true
 
 // MySQLUserList contains a list of MySQLUser
@@ -25,9 +25,80 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedMigration) DeepCopyInto(out *AppliedMigration) {
+	*out = *in
+	in.AppliedAt.DeepCopyInto(&out.AppliedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedMigration.
+func (in *AppliedMigration) DeepCopy() *AppliedMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketConfig) DeepCopyInto(out *BucketConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketConfig.
+func (in *BucketConfig) DeepCopy() *BucketConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapConfig) DeepCopyInto(out *ConfigMapConfig) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapConfig.
+func (in *ConfigMapConfig) DeepCopy() *ConfigMapConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitConfig) DeepCopyInto(out *GitConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitConfig.
+func (in *GitConfig) DeepCopy() *GitConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubConfig) DeepCopyInto(out *GitHubConfig) {
 	*out = *in
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(SecretRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubConfig.
@@ -48,6 +119,12 @@ func (in *Grant) DeepCopyInto(out *Grant) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	out.Object = in.Object
+	if in.Columns != nil {
+		in, out := &in.Columns, &out.Columns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Grant.
@@ -60,12 +137,34 @@ func (in *Grant) DeepCopy() *Grant {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrantTemplateReference) DeepCopyInto(out *GrantTemplateReference) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrantTemplateReference.
+func (in *GrantTemplateReference) DeepCopy() *GrantTemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(GrantTemplateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MySQL) DeepCopyInto(out *MySQL) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
@@ -93,7 +192,7 @@ func (in *MySQLDB) DeepCopyInto(out *MySQLDB) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLDB.
@@ -149,11 +248,43 @@ func (in *MySQLDBList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MySQLDBSpec) DeepCopyInto(out *MySQLDBSpec) {
 	*out = *in
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ReplicaQuota != nil {
+		in, out := &in.ReplicaQuota, &out.ReplicaQuota
+		*out = new(int64)
+		**out = **in
+	}
 	if in.SchemaMigrationFromGitHub != nil {
 		in, out := &in.SchemaMigrationFromGitHub, &out.SchemaMigrationFromGitHub
 		*out = new(GitHubConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchemaMigrationFromConfigMap != nil {
+		in, out := &in.SchemaMigrationFromConfigMap, &out.SchemaMigrationFromConfigMap
+		*out = new(ConfigMapConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchemaMigrationFromGit != nil {
+		in, out := &in.SchemaMigrationFromGit, &out.SchemaMigrationFromGit
+		*out = new(GitConfig)
 		**out = **in
 	}
+	if in.SchemaMigrationFromBucket != nil {
+		in, out := &in.SchemaMigrationFromBucket, &out.SchemaMigrationFromBucket
+		*out = new(BucketConfig)
+		**out = **in
+	}
+	if in.SchemaMigrationPolicy != nil {
+		in, out := &in.SchemaMigrationPolicy, &out.SchemaMigrationPolicy
+		*out = new(SchemaMigrationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLDBSpec.
@@ -170,6 +301,30 @@ func (in *MySQLDBSpec) DeepCopy() *MySQLDBSpec {
 func (in *MySQLDBStatus) DeepCopyInto(out *MySQLDBStatus) {
 	*out = *in
 	out.SchemaMigration = in.SchemaMigration
+	if in.SchemaMigrationJob != nil {
+		in, out := &in.SchemaMigrationJob, &out.SchemaMigrationJob
+		*out = new(SchemaMigrationJob)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchemaMigrationHistory != nil {
+		in, out := &in.SchemaMigrationHistory, &out.SchemaMigrationHistory
+		*out = make([]AppliedMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLDBStatus.
@@ -182,6 +337,194 @@ func (in *MySQLDBStatus) DeepCopy() *MySQLDBStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrant) DeepCopyInto(out *MySQLGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrant.
+func (in *MySQLGrant) DeepCopy() *MySQLGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MySQLGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantList) DeepCopyInto(out *MySQLGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MySQLGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantList.
+func (in *MySQLGrantList) DeepCopy() *MySQLGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MySQLGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantSpec) DeepCopyInto(out *MySQLGrantSpec) {
+	*out = *in
+	if in.UserRef != nil {
+		in, out := &in.UserRef, &out.UserRef
+		*out = new(MySQLUserReference)
+		**out = **in
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]Grant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantSpec.
+func (in *MySQLGrantSpec) DeepCopy() *MySQLGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantStatus) DeepCopyInto(out *MySQLGrantStatus) {
+	*out = *in
+	if in.AppliedGrants != nil {
+		in, out := &in.AppliedGrants, &out.AppliedGrants
+		*out = make([]Grant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantStatus.
+func (in *MySQLGrantStatus) DeepCopy() *MySQLGrantStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantTemplate) DeepCopyInto(out *MySQLGrantTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantTemplate.
+func (in *MySQLGrantTemplate) DeepCopy() *MySQLGrantTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MySQLGrantTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantTemplateList) DeepCopyInto(out *MySQLGrantTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MySQLGrantTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantTemplateList.
+func (in *MySQLGrantTemplateList) DeepCopy() *MySQLGrantTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MySQLGrantTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLGrantTemplateSpec) DeepCopyInto(out *MySQLGrantTemplateSpec) {
+	*out = *in
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]Grant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLGrantTemplateSpec.
+func (in *MySQLGrantTemplateSpec) DeepCopy() *MySQLGrantTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLGrantTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MySQLList) DeepCopyInto(out *MySQLList) {
 	*out = *in
@@ -219,6 +562,11 @@ func (in *MySQLSpec) DeepCopyInto(out *MySQLSpec) {
 	*out = *in
 	out.AdminUser = in.AdminUser
 	out.AdminPassword = in.AdminPassword
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLSpec.
@@ -305,6 +653,21 @@ func (in *MySQLUserList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MySQLUserReference) DeepCopyInto(out *MySQLUserReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLUserReference.
+func (in *MySQLUserReference) DeepCopy() *MySQLUserReference {
+	if in == nil {
+		return nil
+	}
+	out := new(MySQLUserReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MySQLUserSpec) DeepCopyInto(out *MySQLUserSpec) {
 	*out = *in
@@ -316,6 +679,23 @@ func (in *MySQLUserSpec) DeepCopyInto(out *MySQLUserSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GrantTemplates != nil {
+		in, out := &in.GrantTemplates, &out.GrantTemplates
+		*out = make([]GrantTemplateReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AllowedGrantNamespaces != nil {
+		in, out := &in.AllowedGrantNamespaces, &out.AllowedGrantNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLUserSpec.
@@ -338,6 +718,26 @@ func (in *MySQLUserStatus) DeepCopyInto(out *MySQLUserStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedStatements != nil {
+		in, out := &in.AppliedStatements, &out.AppliedStatements
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObservedMySQLGrants != nil {
+		in, out := &in.ObservedMySQLGrants, &out.ObservedMySQLGrants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObservedGrantTemplates != nil {
+		in, out := &in.ObservedGrantTemplates, &out.ObservedGrantTemplates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MySQLUserStatus.
@@ -350,6 +750,21 @@ func (in *MySQLUserStatus) DeepCopy() *MySQLUserStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectRef) DeepCopyInto(out *ObjectRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectRef.
+func (in *ObjectRef) DeepCopy() *ObjectRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SchemaMigration) DeepCopyInto(out *SchemaMigration) {
 	*out = *in
@@ -365,6 +780,49 @@ func (in *SchemaMigration) DeepCopy() *SchemaMigration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaMigrationJob) DeepCopyInto(out *SchemaMigrationJob) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaMigrationJob.
+func (in *SchemaMigrationJob) DeepCopy() *SchemaMigrationJob {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaMigrationJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaMigrationPolicy) DeepCopyInto(out *SchemaMigrationPolicy) {
+	*out = *in
+	if in.TargetVersion != nil {
+		in, out := &in.TargetVersion, &out.TargetVersion
+		*out = new(uint)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaMigrationPolicy.
+func (in *SchemaMigrationPolicy) DeepCopy() *SchemaMigrationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaMigrationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Secret) DeepCopyInto(out *Secret) {
 	*out = *in
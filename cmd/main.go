@@ -20,6 +20,8 @@ import (
 	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,9 +29,15 @@ import (
 	"go.uber.org/zap/zapcore"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -40,6 +48,7 @@ import (
 	controllers "github.com/nakamasato/mysql-operator/internal/controller"
 	"github.com/nakamasato/mysql-operator/internal/mysql"
 	"github.com/nakamasato/mysql-operator/internal/secret"
+	webhookmysqlv1alpha1 "github.com/nakamasato/mysql-operator/internal/webhook/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -62,6 +71,13 @@ func main() {
 	var adminUserSecretType string
 	var projectId string
 	var secretNamespace string
+	var planOnly bool
+	var grantCacheTTL time.Duration
+	var migrationJobImage string
+	var migrationJobNamespace string
+	var migrationJobServiceAccount string
+	var runSchemaMigration string
+	var bucketAmbientCredentials bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -78,6 +94,27 @@ func main() {
 		"Kubernetes namespace where MYSQL credentials secrets is located. Set this value to use adminUserSecretType=k8s. "+
 			"Also can be set by environment variable SECRET_NAMESPACE."+
 			"If both are set, the flag is used.")
+	flag.BoolVar(&planOnly, "plan-only", false,
+		"Only plan the statements for MySQLUser and MySQLDB without executing them. "+
+			"The statements are published to the status and Events.")
+	flag.DurationVar(&grantCacheTTL, "grant-cache-ttl", 0,
+		"How long a snapshot of the grants of all users in a cluster is used for MySQLUser before it is read again, e.g. 30s. "+
+			"Grant drift is detected up to this much later. Default to 0, which reads the grants of each user with SHOW GRANTS.")
+	flag.StringVar(&migrationJobImage, "migration-job-image", os.Getenv("MIGRATION_JOB_IMAGE"),
+		"The image of the operator to run schema migrations in Jobs for MySQLDBs with execution Job. "+
+			"Also can be set by environment variable MIGRATION_JOB_IMAGE. Jobs are disabled if not set.")
+	flag.StringVar(&migrationJobNamespace, "migration-job-namespace", os.Getenv("POD_NAMESPACE"),
+		"The namespace of the migration Jobs, which needs the credentials of the clusters. "+
+			"Default to the namespace of the operator.")
+	flag.StringVar(&migrationJobServiceAccount, "migration-job-service-account", "",
+		"The service account of the migration Jobs, which needs the same permissions as the operator, "+
+			"e.g. the service account of the operator. Default to the default service account of the namespace.")
+	flag.StringVar(&runSchemaMigration, "run-schema-migration", "",
+		"Run the schema migrations of the MySQLDB <namespace>/<name> and exit instead of starting the manager. "+
+			"This is what the migration Jobs run.")
+	flag.BoolVar(&bucketAmbientCredentials, "bucket-ambient-credentials", false,
+		"Allow schema migrations from buckets without secretName to use the credentials of the operator, "+
+			"e.g. IAM roles for service accounts, which then read any bucket the operator can read.")
 	opts := zap.Options{
 		Development: true,
 		TimeEncoder: zapcore.ISO8601TimeEncoder,
@@ -87,12 +124,25 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if adminUserSecretType == "gcp" && projectId == "" {
+		projectId = os.Getenv("PROJECT_ID")
+	}
+	if adminUserSecretType == "k8s" && secretNamespace == "" {
+		secretNamespace = os.Getenv("SECRET_NAMESPACE")
+	}
+
+	if runSchemaMigration != "" {
+		os.Exit(runMigration(runSchemaMigration, adminUserSecretType, projectId, secretNamespace, bucketAmbientCredentials))
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "dfc6d3c2.nakamasato.com",
+		// Leases locking schema migrations are read from the API server, not watched
+		Client: client.Options{Cache: &client.CacheOptions{DisableFor: []client.Object{&coordinationv1.Lease{}}}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -101,60 +151,74 @@ func main() {
 
 	mysqlClients := mysql.MySQLClients{}
 
+	var grantCache *controllers.GrantCache
+	if grantCacheTTL > 0 {
+		grantCache = controllers.NewGrantCache(grantCacheTTL)
+	}
+
 	if err = (&controllers.MySQLUserReconciler{
 		Client:       mgr.GetClient(),
 		Scheme:       mgr.GetScheme(),
 		MySQLClients: mysqlClients,
+		Recorder:     mgr.GetEventRecorderFor("mysqluser-controller"),
+		PlanOnly:     planOnly,
+		GrantCache:   grantCache,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MySQLUser")
 		os.Exit(1)
 	}
 
 	ctx := context.Background()
-	secretManagers := map[string]secret.SecretManager{
-		"raw": secret.RawSecretManager{},
-	}
-	switch adminUserSecretType {
-	case "gcp":
-		if projectId == "" {
-			projectId = os.Getenv("PROJECT_ID")
-		}
-		gcpSecretManager, err := secret.NewGCPSecretManager(ctx, projectId)
-		if err != nil {
-			setupLog.Error(err, "failed to initialize GCPSecretManager")
-			os.Exit(1)
-		}
-		defer gcpSecretManager.Close()
-		setupLog.Info("Initialized gcpSecretManager", "projectId", projectId)
-		secretManagers["gcp"] = gcpSecretManager
-	case "k8s":
-		if secretNamespace == "" {
-			secretNamespace = os.Getenv("SECRET_NAMESPACE")
-		}
-		k8sSecretManager, err := secret.Newk8sSecretManager(ctx, secretNamespace, mgr.GetClient())
-		if err != nil {
-			setupLog.Error(err, "failed to initialize k8sSecretManager")
-			os.Exit(1)
-		}
-		setupLog.Info("Initialized k8sSecretManager", "namespace", secretNamespace)
-		secretManagers["k8s"] = k8sSecretManager
-	}
+	secretManagers, closeSecretManagers := newSecretManagers(ctx, adminUserSecretType, projectId, secretNamespace, mgr.GetClient())
+	defer closeSecretManagers()
 	if err = (&controllers.MySQLReconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
 		MySQLClients:    mysqlClients,
 		MySQLDriverName: "mysql",
 		SecretManagers:  secretManagers,
+		Recorder:        mgr.GetEventRecorderFor("mysql-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MySQL")
 		os.Exit(1)
 	}
+	var migrationJob *controllers.MigrationJobConfig
+	if migrationJobImage != "" {
+		migrationJob = &controllers.MigrationJobConfig{
+			Namespace:          migrationJobNamespace,
+			Image:              migrationJobImage,
+			ServiceAccountName: migrationJobServiceAccount,
+			Args:               secretManagerArgs(adminUserSecretType, projectId, secretNamespace),
+		}
+		if bucketAmbientCredentials {
+			migrationJob.Args = append(migrationJob.Args, "--bucket-ambient-credentials")
+		}
+		if migrationJob.Namespace == "" {
+			migrationJob.Namespace = currentNamespace()
+		}
+		setupLog.Info("Enabled migration Jobs", "namespace", migrationJob.Namespace, "image", migrationJobImage)
+	}
 	if err = (&controllers.MySQLDBReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		MySQLClients:             mysqlClients,
+		Recorder:                 mgr.GetEventRecorderFor("mysqldb-controller"),
+		PlanOnly:                 planOnly,
+		MigrationJob:             migrationJob,
+		Clientset:                kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+		BucketAmbientCredentials: bucketAmbientCredentials,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MySQLDB")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.MySQLGrantReconciler{
 		Client:       mgr.GetClient(),
 		Scheme:       mgr.GetScheme(),
 		MySQLClients: mysqlClients,
+		Recorder:     mgr.GetEventRecorderFor("mysqlgrant-controller"),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "MySQLDB")
+		setupLog.Error(err, "unable to create controller", "controller", "MySQLGrant")
 		os.Exit(1)
 	}
 
@@ -174,6 +238,13 @@ func main() {
 		panic(err)
 	}
 
+	// Webhooks need the serving certificates, e.g. issued by cert-manager with config/default
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = webhookmysqlv1alpha1.SetupMySQLUserWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MySQLUser")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -191,3 +262,90 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newSecretManagers returns the secret managers to read the credentials of
+// the clusters from, and the function to close them
+func newSecretManagers(ctx context.Context, adminUserSecretType, projectId, secretNamespace string, c client.Client) (map[string]secret.SecretManager, func()) {
+	secretManagers := map[string]secret.SecretManager{
+		"raw": secret.RawSecretManager{},
+	}
+	closeFunc := func() {}
+	switch adminUserSecretType {
+	case "gcp":
+		gcpSecretManager, err := secret.NewGCPSecretManager(ctx, projectId)
+		if err != nil {
+			setupLog.Error(err, "failed to initialize GCPSecretManager")
+			os.Exit(1)
+		}
+		closeFunc = func() { gcpSecretManager.Close() }
+		setupLog.Info("Initialized gcpSecretManager", "projectId", projectId)
+		secretManagers["gcp"] = gcpSecretManager
+	case "k8s":
+		k8sSecretManager, err := secret.Newk8sSecretManager(ctx, secretNamespace, c)
+		if err != nil {
+			setupLog.Error(err, "failed to initialize k8sSecretManager")
+			os.Exit(1)
+		}
+		setupLog.Info("Initialized k8sSecretManager", "namespace", secretNamespace)
+		secretManagers["k8s"] = k8sSecretManager
+	}
+	return secretManagers, closeFunc
+}
+
+// secretManagerArgs returns the flags for the migration Jobs to read the
+// credentials of the clusters as the operator does
+func secretManagerArgs(adminUserSecretType, projectId, secretNamespace string) []string {
+	args := []string{"--admin-user-secret-type=" + adminUserSecretType}
+	if projectId != "" {
+		args = append(args, "--gcp-project-id="+projectId)
+	}
+	if secretNamespace != "" {
+		args = append(args, "--k8s-secret-namespace="+secretNamespace)
+	}
+	return args
+}
+
+// currentNamespace returns the namespace of the service account of the Pod
+func currentNamespace() string {
+	namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		setupLog.Error(err, "failed to read the namespace of the operator: set --migration-job-namespace")
+		os.Exit(1)
+	}
+	return strings.TrimSpace(string(namespace))
+}
+
+// runMigration runs the schema migrations of the MySQLDB <namespace>/<name>
+// and returns the exit code
+func runMigration(mysqlDB, adminUserSecretType, projectId, secretNamespace string, bucketAmbientCredentials bool) int {
+	log := ctrl.Log.WithName("migration")
+	namespace, name, ok := strings.Cut(mysqlDB, "/")
+	if !ok {
+		log.Error(nil, "MySQLDB must be <namespace>/<name>", "mysqlDB", mysqlDB)
+		return 1
+	}
+	cfg := ctrl.GetConfigOrDie()
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		log.Error(err, "unable to create client")
+		return 1
+	}
+	ctx := ctrl.LoggerInto(ctrl.SetupSignalHandler(), log)
+	secretManagers, closeSecretManagers := newSecretManagers(ctx, adminUserSecretType, projectId, secretNamespace, c)
+	defer closeSecretManagers()
+
+	// Events are recorded as the operator does
+	broadcaster := record.NewBroadcaster()
+	defer broadcaster.Shutdown()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubernetes.NewForConfigOrDie(cfg).CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "mysqldb-migration-job"})
+
+	start := time.Now()
+	err = controllers.RunSchemaMigration(ctx, c, secretManagers, recorder, types.NamespacedName{Namespace: namespace, Name: name}, bucketAmbientCredentials)
+	if err != nil {
+		log.Error(err, "failed to migrate", "mysqlDB", mysqlDB, "duration", time.Since(start).String())
+		return 1
+	}
+	log.Info("migrated", "mysqlDB", mysqlDB, "duration", time.Since(start).String())
+	return 0
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// fakeDB is a database that records the executed statements, fails the
+// statements matched by failOn, and answers queries with rows.
+// Unlike testdbdriver, it lets a test script the outcome of each statement.
+type fakeDB struct {
+	mu         sync.Mutex
+	statements []string
+	queries    []string
+	closed     int
+	failOn     func(statement string) error
+	rows       func(query string) (columns []string, rows [][]driver.Value)
+}
+
+var fakeDBs sync.Map
+
+func init() {
+	sql.Register("fakedb", fakeDBDriver{})
+}
+
+// newFakeDB returns a *sql.DB backed by a new fakeDB
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	dsn := fmt.Sprintf("%p", fake)
+	fakeDBs.Store(dsn, fake)
+	db, err := sql.Open("fakedb", dsn)
+	if err != nil {
+		panic(err)
+	}
+	return db, fake
+}
+
+// Statements returns the statements executed so far
+func (f *fakeDB) Statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.statements...)
+}
+
+// Closed returns the number of connections closed instead of returned to the pool
+func (f *fakeDB) Closed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+type fakeDBDriver struct{}
+
+func (fakeDBDriver) Open(dsn string) (driver.Conn, error) {
+	fake, ok := fakeDBs.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("unknown fakedb %s", dsn)
+	}
+	return &fakeDBConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeDBConn struct {
+	db *fakeDB
+}
+
+func (c *fakeDBConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakedb doesn't prepare statements")
+}
+
+func (c *fakeDBConn) Close() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.closed++
+	return nil
+}
+
+func (c *fakeDBConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("fakedb doesn't support transactions")
+}
+
+func (c *fakeDBConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	failOn := c.db.failOn
+	c.db.mu.Unlock()
+	if failOn != nil {
+		if err := failOn(query); err != nil {
+			return nil, err
+		}
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeDBConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	c.db.queries = append(c.db.queries, query)
+	failOn, rows := c.db.failOn, c.db.rows
+	c.db.mu.Unlock()
+	if failOn != nil {
+		if err := failOn(query); err != nil {
+			return nil, err
+		}
+	}
+	result := &fakeDBRows{}
+	if rows != nil {
+		result.columns, result.rows = rows(query)
+	}
+	return result, nil
+}
+
+type fakeDBRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeDBRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeDBRows) Close() error {
+	return nil
+}
+
+func (r *fakeDBRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	"github.com/nakamasato/mysql-operator/internal/metrics"
+)
+
+// Grants of all users in StarRocks, one row per object and privilege.
+// Users without grants are not listed.
+const starRocksAllGrantsQuery = "SELECT GRANTEE, OBJECT_CATALOG, OBJECT_DATABASE, OBJECT_NAME, OBJECT_TYPE, PRIVILEGE_TYPE, IS_GRANTABLE FROM sys.grants_to_users"
+
+// GrantCache keeps a snapshot of the grants of all users per cluster, so that
+// the users of a cluster are diffed against one query instead of SHOW GRANTS
+// for each user. A snapshot is read again after TTL, and a user is dropped
+// from the snapshot when the operator changes the user or its grants.
+type GrantCache struct {
+	TTL time.Duration
+
+	// mu guards clusters, and each cluster has its own lock so that a slow
+	// snapshot of a cluster doesn't block the users of the other clusters
+	mu       sync.Mutex
+	clusters map[string]*clusterGrants
+	now      func() time.Time
+	take     func(context.Context, *sql.DB) (*grantSnapshot, error)
+}
+
+// clusterGrants is the snapshot of a cluster. Its lock is held while the
+// snapshot is taken, so that it is taken once for the users waiting for it.
+type clusterGrants struct {
+	mu       sync.Mutex
+	snapshot *grantSnapshot
+	// failedAt is when the snapshot failed, e.g. without the privilege to read
+	// sys.grants_to_users, so that it is not taken again until TTL passes
+	failedAt time.Time
+}
+
+type grantSnapshot struct {
+	dialect Dialect
+	// grants by user identity without quotes, e.g. user@%
+	grants  map[string][]mysqlv1alpha1.Grant
+	takenAt time.Time
+}
+
+// NewGrantCache returns a GrantCache whose snapshots are used for ttl
+func NewGrantCache(ttl time.Duration) *GrantCache {
+	return &GrantCache{TTL: ttl, clusters: map[string]*clusterGrants{}, now: time.Now, take: takeGrantSnapshot}
+}
+
+// Grants returns the grants of the user from the snapshot of the cluster,
+// taking the snapshot if there is none or it is older than TTL.
+// It returns false if the user is not in the snapshot, and for TTL after
+// the snapshot failed, returning the error only when the snapshot fails.
+func (c *GrantCache) Grants(ctx context.Context, clusterKey string, mysqlClient *sql.DB, userIdentity string) ([]mysqlv1alpha1.Grant, Dialect, bool, error) {
+	cluster := c.cluster(clusterKey)
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+
+	if cluster.snapshot == nil && !cluster.failedAt.IsZero() && c.now().Sub(cluster.failedAt) < c.TTL {
+		return nil, "", false, nil
+	}
+
+	snapshot := cluster.snapshot
+	fresh := snapshot != nil && c.now().Sub(snapshot.takenAt) < c.TTL
+	if !fresh {
+		var err error
+		if snapshot, err = c.take(ctx, mysqlClient); err != nil {
+			cluster.snapshot = nil
+			cluster.failedAt = c.now()
+			return nil, "", false, err
+		}
+		snapshot.takenAt = c.now()
+		cluster.snapshot = snapshot
+		cluster.failedAt = time.Time{}
+		log.FromContext(ctx).Info("[GrantCache] Took snapshot of grants", "clusterKey", clusterKey, "users", len(snapshot.grants))
+	}
+
+	grants, ok := snapshot.grants[userIdentityKey(userIdentity)]
+	// The query that took the snapshot saves nothing for this user
+	if ok && fresh {
+		metrics.MysqlUserGrantStatementsSavedTotal.Increment()
+	}
+	return grants, snapshot.dialect, ok, nil
+}
+
+// Invalidate drops the user from the snapshot of the cluster so that the
+// grants of the user are read from the cluster until the next snapshot.
+// It waits for a snapshot being taken, which might have the old grants.
+func (c *GrantCache) Invalidate(clusterKey, userIdentity string) {
+	cluster := c.cluster(clusterKey)
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if cluster.snapshot != nil {
+		delete(cluster.snapshot.grants, userIdentityKey(userIdentity))
+	}
+}
+
+// cluster returns the snapshot of the cluster, adding an empty one if there is none
+func (c *GrantCache) cluster(clusterKey string) *clusterGrants {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster, ok := c.clusters[clusterKey]
+	if !ok {
+		cluster = &clusterGrants{}
+		c.clusters[clusterKey] = cluster
+	}
+	return cluster
+}
+
+// takeGrantSnapshot reads the grants of all users, from sys.grants_to_users in
+// StarRocks and SHOW ALL GRANTS in Doris
+func takeGrantSnapshot(ctx context.Context, mysqlClient *sql.DB) (*grantSnapshot, error) {
+	dialect, err := detectDialect(ctx, mysqlClient)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &grantSnapshot{dialect: dialect, grants: map[string][]mysqlv1alpha1.Grant{}}
+
+	if dialect == DialectStarRocks {
+		rows, err := mysqlClient.QueryContext(ctx, starRocksAllGrantsQuery)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var grantee, catalog, database, name, objectType, privileges, grantable sql.NullString
+			if err := rows.Scan(&grantee, &catalog, &database, &name, &objectType, &privileges, &grantable); err != nil {
+				return nil, err
+			}
+			key := userIdentityKey(grantee.String)
+			snapshot.grants[key] = append(snapshot.grants[key], mysqlv1alpha1.Grant{
+				Privileges:  normalizePerms(splitTopLevel(privileges.String, ',')),
+				Object:      starRocksSysObject(objectType.String, catalog, database, name),
+				GrantOption: strings.EqualFold(grantable.String, "YES"),
+			})
+		}
+		return snapshot, rows.Err()
+	}
+
+	rows, err := mysqlClient.QueryContext(ctx, "SHOW ALL GRANTS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		row, err := scanDorisGrant(rows, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		grants, err := buildDorisGrants(row)
+		if err != nil {
+			return nil, err
+		}
+		// Users without grants are kept so that they are known to the snapshot
+		key := userIdentityKey(row.UserIdentity.String)
+		snapshot.grants[key] = append(snapshot.grants[key], grants...)
+	}
+	return snapshot, rows.Err()
+}
+
+// starRocksSysObject converts an object in sys.grants_to_users into an object.
+// A missing database or name stands for all of them, as in ALL TABLES IN ALL DATABASES.
+func starRocksSysObject(objectType string, catalog, database, name sql.NullString) mysqlv1alpha1.ObjectRef {
+	orAll := func(s sql.NullString) string {
+		if !s.Valid || s.String == "" {
+			return mysqlv1alpha1.ObjectNameAll
+		}
+		return s.String
+	}
+	kind := mysqlv1alpha1.ObjectKind(strings.ToUpper(strings.Join(strings.Fields(objectType), " ")))
+	switch kind {
+	case mysqlv1alpha1.ObjectKindSystem:
+		return mysqlv1alpha1.ObjectRef{Kind: kind}
+	case mysqlv1alpha1.ObjectKindCatalog:
+		return mysqlv1alpha1.ObjectRef{Kind: kind, Name: orAll(catalog)}
+	case mysqlv1alpha1.ObjectKindDatabase:
+		return mysqlv1alpha1.ObjectRef{Kind: kind, Catalog: catalog.String, Name: orAll(database)}
+	case mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+		return mysqlv1alpha1.ObjectRef{Kind: kind, Catalog: catalog.String, Database: orAll(database), Name: orAll(name)}
+	case mysqlv1alpha1.ObjectKindFunction:
+		return mysqlv1alpha1.ObjectRef{Kind: kind, Database: database.String, Name: orAll(name)}
+	case "GLOBAL FUNCTION":
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: orAll(name)}
+	default:
+		return mysqlv1alpha1.ObjectRef{Kind: kind, Name: orAll(name)}
+	}
+}
+
+// userIdentityKey returns the user identity without quotes, e.g. user@% for 'user'@'%'
+func userIdentityKey(userIdentity string) string {
+	return strings.NewReplacer("'", "", "`", "", `"`, "").Replace(strings.TrimSpace(userIdentity))
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var _ = Describe("GrantCache", func() {
+	table := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}
+
+	It("Should return grants from the snapshot until the user is invalidated", func() {
+		now := time.Now()
+		cache := NewGrantCache(time.Minute)
+		cache.now = func() time.Time { return now }
+		cache.cluster("cluster").snapshot = &grantSnapshot{
+			dialect: DialectStarRocks,
+			grants:  map[string][]mysqlv1alpha1.Grant{"app@%": {{Privileges: []string{"SELECT"}, Object: table}}},
+			takenAt: now,
+		}
+
+		// The snapshot is fresh, so no query is needed
+		grants, dialect, ok, err := cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(dialect).To(Equal(DialectStarRocks))
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: table}}))
+
+		// Users not in the snapshot are read with SHOW GRANTS
+		_, _, ok, err = cache.Grants(context.TODO(), "cluster", nil, "'other'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		cache.Invalidate("cluster", "'app'@'%'")
+		_, _, ok, err = cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should count the statements saved only by a fresh snapshot", func() {
+		savedTotal := func() float64 {
+			families, err := ctrlmetrics.Registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			for _, family := range families {
+				if family.GetName() == "mysqloperator_mysql_user_grant_statements_saved_total" {
+					return family.GetMetric()[0].GetCounter().GetValue()
+				}
+			}
+			return 0
+		}
+		cache := NewGrantCache(time.Minute)
+		cache.take = func(context.Context, *sql.DB) (*grantSnapshot, error) {
+			return &grantSnapshot{dialect: DialectStarRocks, grants: map[string][]mysqlv1alpha1.Grant{"app@%": nil}}, nil
+		}
+		saved := savedTotal()
+
+		// The query that took the snapshot replaced SHOW GRANTS of the user
+		_, _, ok, err := cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(savedTotal()).To(Equal(saved))
+
+		_, _, ok, err = cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(savedTotal()).To(Equal(saved + 1))
+	})
+
+	It("Should not take a snapshot again until TTL passes after it failed", func() {
+		now := time.Now()
+		var taken int
+		cache := NewGrantCache(time.Minute)
+		cache.now = func() time.Time { return now }
+		cache.take = func(context.Context, *sql.DB) (*grantSnapshot, error) {
+			taken++
+			if taken == 1 {
+				return nil, context.DeadlineExceeded
+			}
+			return &grantSnapshot{dialect: DialectStarRocks, grants: map[string][]mysqlv1alpha1.Grant{"app@%": nil}}, nil
+		}
+
+		_, _, ok, err := cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(ok).To(BeFalse())
+
+		// The users are read with SHOW GRANTS without the error until TTL passes
+		now = now.Add(30 * time.Second)
+		_, _, ok, err = cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(taken).To(Equal(1))
+
+		now = now.Add(30 * time.Second)
+		_, _, ok, err = cache.Grants(context.TODO(), "cluster", nil, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(taken).To(Equal(2))
+	})
+
+	It("Should take a snapshot once for a cluster without blocking the other clusters", func() {
+		slow, fast := &sql.DB{}, &sql.DB{}
+		release := make(chan struct{})
+		var taken atomic.Int32
+		cache := NewGrantCache(time.Minute)
+		cache.take = func(_ context.Context, mysqlClient *sql.DB) (*grantSnapshot, error) {
+			taken.Add(1)
+			if mysqlClient == slow {
+				<-release
+			}
+			return &grantSnapshot{dialect: DialectStarRocks, grants: map[string][]mysqlv1alpha1.Grant{}}, nil
+		}
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, _, _, err := cache.Grants(context.TODO(), "slow", slow, "'app'@'%'")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		Eventually(taken.Load).Should(BeEquivalentTo(1))
+
+		_, _, _, err := cache.Grants(context.TODO(), "fast", fast, "'app'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taken.Load()).To(BeEquivalentTo(2))
+
+		close(release)
+		wg.Wait()
+		Expect(taken.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("Should convert objects in sys.grants_to_users", func() {
+		null := sql.NullString{}
+		str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+		Expect(starRocksSysObject("SYSTEM", null, null, null)).To(Equal(mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem}))
+		Expect(starRocksSysObject("TABLE", str("default_catalog"), str("db1"), str("tbl1"))).To(Equal(
+			mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "default_catalog", Database: "db1", Name: "tbl1"}))
+		Expect(starRocksSysObject("TABLE", str("default_catalog"), str("db1"), null)).To(Equal(
+			mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "default_catalog", Database: "db1", Name: "*"}))
+		Expect(starRocksSysObject("DATABASE", str("hive"), null, null)).To(Equal(
+			mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: "hive", Name: "*"}))
+		Expect(starRocksSysObject("MATERIALIZED VIEW", str("default_catalog"), str("db1"), str("mv1"))).To(Equal(
+			mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindMaterializedView, Catalog: "default_catalog", Database: "db1", Name: "mv1"}))
+		Expect(starRocksSysObject("GLOBAL FUNCTION", null, null, str("f(INT)"))).To(Equal(
+			mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: "f(INT)"}))
+
+		// Objects converted from sys.grants_to_users and SHOW GRANTS are the same after normalization
+		fromSys := []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: starRocksSysObject("TABLE", str("default_catalog"), str("db1"), null)}}
+		fromShowGrants := parseStarRocksGrant("default_catalog", "GRANT SELECT ON ALL TABLES IN DATABASE db1 TO USER 'app'@'%'")
+		revoke, add := diffGrants(DialectStarRocks, fromSys, fromShowGrants)
+		Expect(revoke).To(BeEmpty())
+		Expect(add).To(BeEmpty())
+	})
+
+	It("Should key users without quotes", func() {
+		Expect(userIdentityKey("'app'@'%'")).To(Equal("app@%"))
+		Expect(userIdentityKey("`app`@`10.0.0.1`")).To(Equal("app@10.0.0.1"))
+	})
+})
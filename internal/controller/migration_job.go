@@ -0,0 +1,309 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	"github.com/nakamasato/mysql-operator/internal/secret"
+)
+
+const (
+	migrationJobLabelNamespace = "mysqldb.nakamasato.com/namespace"
+	migrationJobLabelName      = "mysqldb.nakamasato.com/name"
+	migrationJobPhaseRunning   = "Running"
+	migrationJobPhaseSucceeded = "Succeeded"
+	migrationJobPhaseFailed    = "Failed"
+	// Lines of the logs of a Job kept in the status
+	migrationJobLogLines = 20
+	// Finished Jobs are kept for a day to look into them
+	migrationJobTTLSeconds = 24 * 60 * 60
+
+	mysqlDBEventReasonMigrationJobStarted   = "StartedMigrationJob"
+	mysqlDBEventReasonMigrationJobSucceeded = "MigrationJobSucceeded"
+	mysqlDBEventReasonMigrationJobFailed    = "MigrationJobFailed"
+)
+
+// MigrationJobConfig is how the operator launches the Jobs that run schema migrations.
+// The Jobs run the operator image with --run-schema-migration.
+type MigrationJobConfig struct {
+	// Namespace of the Jobs, which is the namespace of the operator so that
+	// the Jobs can read the credentials of the clusters as the operator does
+	Namespace string
+	// Image of the operator
+	Image string
+	// ServiceAccountName of the operator
+	ServiceAccountName string
+	// Arguments to read the credentials of the clusters, e.g. --admin-user-secret-type=gcp
+	Args []string
+}
+
+// reconcileMigrationJob launches a Job to run the schema migrations of the
+// MySQLDB, and tracks its outcome in the status until it finishes. A Job runs
+// once for each generation of the MySQLDB, forced version and revision of the source.
+func (r *MySQLDBReconciler) reconcileMigrationJob(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if r.MigrationJob == nil {
+		err := fmt.Errorf("schema migrations in Jobs are not enabled in the operator: set --migration-job-image")
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToMigrate, "Failed to migrate database %s: %v", mysqlDB.GetQualifiedName(), err)
+		return ctrl.Result{}, err
+	}
+
+	name, err := r.migrationJobName(ctx, mysqlDB)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	previous := mysqlDB.Status.SchemaMigrationJob
+	if previous != nil && previous.Name == name && previous.Phase != migrationJobPhaseRunning {
+		// The Job already ran, and might be deleted after its TTL
+		return ctrl.Result{}, nil
+	}
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: r.MigrationJob.Namespace, Name: name}, job)
+	if errors.IsNotFound(err) {
+		job = r.newMigrationJob(mysqlDB, name)
+		if err := r.Create(ctx, job); err != nil {
+			log.Error(err, "[MigrationJob] Failed to create Job", "job", name)
+			return ctrl.Result{}, err
+		}
+		log.Info("[MigrationJob] Created Job", "job", name)
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonMigrationJobStarted, "Started Job %s/%s to migrate database %s", job.Namespace, name, mysqlDB.GetQualifiedName())
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := migrationJobStatus(job)
+	if previous != nil && previous.Name == name {
+		status.ForcedVersion = previous.ForcedVersion
+	} else {
+		status.ForcedVersion = mysqlDB.Annotations[mysqlDBForceVersionAnnotation]
+	}
+	if status.Phase != migrationJobPhaseRunning {
+		// The operator removes the annotation in case the Job failed before removing it,
+		// so that a failed Job doesn't force the version again
+		if version, ok := mysqlDB.Annotations[mysqlDBForceVersionAnnotation]; ok && status.ForcedVersion == version {
+			delete(mysqlDB.Annotations, mysqlDBForceVersionAnnotation)
+			if err := r.Update(ctx, mysqlDB); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		status.Logs = r.migrationJobLogs(ctx, job)
+		if status.Phase == migrationJobPhaseSucceeded {
+			r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonMigrationJobSucceeded, "Job %s/%s migrated database %s in %s", job.Namespace, name, mysqlDB.GetQualifiedName(), status.Duration)
+		} else {
+			r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonMigrationJobFailed, "Job %s/%s failed to migrate database %s: see the logs in the status", job.Namespace, name, mysqlDB.GetQualifiedName())
+		}
+	}
+	if equality.Semantic.DeepEqual(previous, status) {
+		return ctrl.Result{}, nil
+	}
+	log.Info("[MigrationJob] Update status", "job", name, "phase", status.Phase)
+	mysqlDB.Status.SchemaMigrationJob = status
+	if err := r.Status().Update(ctx, mysqlDB); err != nil {
+		log.Error(err, "[MigrationJob] Failed to update MySQLDB status", "job", name)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// migrationJobName returns the name of the Job for the MySQLDB, which changes
+// with what needs another run of the migrations.
+// A Job forcing the version is named after the Job before it, so that forcing
+// the same version again launches another Job, and keeps its name after the
+// annotation is removed until the generation or the revision changes.
+func (r *MySQLDBReconciler) migrationJobName(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) (string, error) {
+	revision, err := r.sourceRevision(ctx, mysqlDB)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the revision of the migration files: %w", err)
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%s/%d//%s", mysqlDB.Namespace, mysqlDB.Name, mysqlDB.Generation, revision)
+	// Keep the name within 63 characters for the job-name label of the Pods
+	name := fmt.Sprintf("%s-migrate-%08x", truncateName(mysqlDB.Name, 45), h.Sum32())
+	forcedPrefix := fmt.Sprintf("%s-migrate-%08x-", truncateName(mysqlDB.Name, 36), h.Sum32())
+
+	previous := mysqlDB.Status.SchemaMigrationJob
+	version, forcing := mysqlDB.Annotations[mysqlDBForceVersionAnnotation]
+	switch {
+	case forcing && previous != nil && previous.Phase == migrationJobPhaseRunning && previous.ForcedVersion == version && strings.HasPrefix(previous.Name, forcedPrefix):
+		return previous.Name, nil
+	case forcing:
+		previousName := ""
+		if previous != nil {
+			previousName = previous.Name
+		}
+		f := fnv.New32a()
+		fmt.Fprintf(f, "%s/%s", version, previousName)
+		return fmt.Sprintf("%s%08x", forcedPrefix, f.Sum32()), nil
+	case previous != nil && strings.HasPrefix(previous.Name, forcedPrefix):
+		return previous.Name, nil
+	}
+	return name, nil
+}
+
+// truncateName returns the name cut to the length without a trailing hyphen
+func truncateName(name string, length int) string {
+	if len(name) > length {
+		name = name[:length]
+	}
+	return strings.TrimSuffix(name, "-")
+}
+
+// newMigrationJob returns the Job that runs the schema migrations of the MySQLDB.
+// A failed migration leaves the database dirty, so the Job is never retried.
+func (r *MySQLDBReconciler) newMigrationJob(mysqlDB *mysqlv1alpha1.MySQLDB, name string) *batchv1.Job {
+	labels := map[string]string{
+		migrationJobLabelNamespace: mysqlDB.Namespace,
+		migrationJobLabelName:      mysqlDB.Name,
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.MigrationJob.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](0),
+			TTLSecondsAfterFinished: ptr.To[int32](migrationJobTTLSeconds),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: r.MigrationJob.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:    "migrate",
+						Image:   r.MigrationJob.Image,
+						Command: []string{"/manager"},
+						Args:    append(append([]string{}, r.MigrationJob.Args...), "--run-schema-migration="+mysqlDB.Namespace+"/"+mysqlDB.Name),
+					}},
+				},
+			},
+		},
+	}
+}
+
+// migrationJobStatus returns the phase, times and duration of the Job
+func migrationJobStatus(job *batchv1.Job) *mysqlv1alpha1.SchemaMigrationJob {
+	status := &mysqlv1alpha1.SchemaMigrationJob{Name: job.Name, Phase: migrationJobPhaseRunning, StartTime: job.Status.StartTime}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Phase = migrationJobPhaseSucceeded
+		case batchv1.JobFailed:
+			status.Phase = migrationJobPhaseFailed
+		default:
+			continue
+		}
+		status.CompletionTime = job.Status.CompletionTime
+		if status.CompletionTime == nil {
+			// Failed Jobs have no completion time
+			status.CompletionTime = condition.LastTransitionTime.DeepCopy()
+		}
+		if status.StartTime != nil {
+			status.Duration = status.CompletionTime.Sub(status.StartTime.Time).Round(time.Second).String()
+		}
+	}
+	return status
+}
+
+// migrationJobLogs returns the last lines of the logs of the Pod of the Job.
+// Failures are logged and not returned, as the logs are only informative.
+func (r *MySQLDBReconciler) migrationJobLogs(ctx context.Context, job *batchv1.Job) string {
+	log := log.FromContext(ctx)
+	if r.Clientset == nil {
+		return ""
+	}
+	pods, err := r.Clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+	if err != nil {
+		log.Error(err, "[MigrationJob] Failed to list Pods of Job", "job", job.Name)
+		return ""
+	}
+	if len(pods.Items) == 0 {
+		log.Info("[MigrationJob] No Pod of Job", "job", job.Name)
+		return ""
+	}
+	stream, err := r.Clientset.CoreV1().Pods(job.Namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{
+		TailLines: ptr.To[int64](migrationJobLogLines),
+	}).Stream(ctx)
+	if err != nil {
+		log.Error(err, "[MigrationJob] Failed to get logs of Job", "job", job.Name)
+		return ""
+	}
+	defer stream.Close()
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		log.Error(err, "[MigrationJob] Failed to read logs of Job", "job", job.Name)
+	}
+	return string(logs)
+}
+
+// mysqlDBForMigrationJob enqueues the MySQLDB of the migration Job
+func (r *MySQLDBReconciler) mysqlDBForMigrationJob(ctx context.Context, obj client.Object) []reconcile.Request {
+	namespace, name := obj.GetLabels()[migrationJobLabelNamespace], obj.GetLabels()[migrationJobLabelName]
+	if namespace == "" || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// RunSchemaMigration runs the schema migrations of the MySQLDB as the
+// reconciler does, connecting to the cluster with the credentials read from
+// the secret managers. This is what the migration Jobs run.
+func RunSchemaMigration(ctx context.Context, c client.Client, secretManagers map[string]secret.SecretManager, recorder record.EventRecorder, key types.NamespacedName, bucketAmbientCredentials bool) error {
+	mysqlDB := &mysqlv1alpha1.MySQLDB{}
+	if err := c.Get(ctx, key, mysqlDB); err != nil {
+		return err
+	}
+	mysql := &mysqlv1alpha1.MySQL{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: mysqlDB.Namespace, Name: mysqlDB.Spec.ClusterName}, mysql); err != nil {
+		return err
+	}
+	cfg, err := getMySQLConfig(ctx, secretManagers, mysql)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	r := &MySQLDBReconciler{Client: c, Recorder: recorder, BucketAmbientCredentials: bucketAmbientCredentials}
+	return r.runSchemaMigration(ctx, mysqlDB, mysql, db)
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("MySQLDB migration Job", func() {
+	var mysqlDB *mysqlv1alpha1.MySQLDB
+	var reconciler *MySQLDBReconciler
+	ctx := context.TODO()
+	BeforeEach(func() {
+		mysqlDB = &mysqlv1alpha1.MySQLDB{
+			ObjectMeta: metav1.ObjectMeta{Name: "sales", Namespace: "team-a", Generation: 2},
+			Spec: mysqlv1alpha1.MySQLDBSpec{
+				DBName:                       "sales",
+				SchemaMigrationFromConfigMap: &mysqlv1alpha1.ConfigMapConfig{Names: []string{"sales"}},
+				SchemaMigrationPolicy:        &mysqlv1alpha1.SchemaMigrationPolicy{Execution: mysqlv1alpha1.SchemaMigrationExecutionJob},
+			},
+		}
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "sales", Namespace: "team-a"}}
+		reconciler = &MySQLDBReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysqlDB, configMap).WithStatusSubresource(mysqlDB).Build(),
+			Recorder: record.NewFakeRecorder(10),
+			MigrationJob: &MigrationJobConfig{
+				Namespace:          "mysql-operator",
+				Image:              "mysql-operator:v1",
+				ServiceAccountName: "controller-manager",
+				Args:               []string{"--admin-user-secret-type=k8s"},
+			},
+			Clientset: kubefake.NewSimpleClientset(),
+		}
+	})
+
+	It("Should launch a Job and record its outcome", func() {
+		_, err := reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mysqlDB.Status.SchemaMigrationJob).NotTo(BeNil())
+		Expect(mysqlDB.Status.SchemaMigrationJob.Phase).To(Equal(migrationJobPhaseRunning))
+
+		name := mysqlDB.Status.SchemaMigrationJob.Name
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "mysql-operator", Name: name}, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.ServiceAccountName).To(Equal("controller-manager"))
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("mysql-operator:v1"))
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"--admin-user-secret-type=k8s", "--run-schema-migration=team-a/sales"}))
+		Expect(reconciler.mysqlDBForMigrationJob(ctx, job)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "sales"}},
+		}))
+
+		// The Job and its Pod finish
+		_, err = reconciler.Clientset.CoreV1().Pods("mysql-operator").Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-abcde", Namespace: "mysql-operator", Labels: map[string]string{"job-name": name}},
+		}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		start := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		completion := metav1.NewTime(start.Add(90 * time.Second))
+		job.Status = batchv1.JobStatus{
+			StartTime:      &start,
+			CompletionTime: &completion,
+			Conditions:     []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mysqlDB.Status.SchemaMigrationJob.Phase).To(Equal(migrationJobPhaseSucceeded))
+		Expect(mysqlDB.Status.SchemaMigrationJob.Duration).To(Equal("1m30s"))
+		Expect(mysqlDB.Status.SchemaMigrationJob.Logs).To(Equal("fake logs"))
+
+		// The Job isn't launched again after it is deleted by its TTL
+		Expect(reconciler.Delete(ctx, job)).To(Succeed())
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})).NotTo(Succeed())
+	})
+
+	It("Should keep the Job forcing the version after the annotation is removed", func() {
+		mysqlDB.Annotations = map[string]string{mysqlDBForceVersionAnnotation: "1"}
+		Expect(reconciler.Update(ctx, mysqlDB)).To(Succeed())
+		_, err := reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		forced := mysqlDB.Status.SchemaMigrationJob.Name
+		Expect(mysqlDB.Status.SchemaMigrationJob.ForcedVersion).To(Equal("1"))
+
+		// The Job forces the version and removes the annotation while it's running
+		delete(mysqlDB.Annotations, mysqlDBForceVersionAnnotation)
+		Expect(reconciler.Update(ctx, mysqlDB)).To(Succeed())
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		jobs := &batchv1.JobList{}
+		Expect(reconciler.List(ctx, jobs, client.InNamespace("mysql-operator"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(mysqlDB.Status.SchemaMigrationJob.Name).To(Equal(forced))
+
+		// The Job is kept after it finishes
+		job := &jobs.Items[0]
+		job.Status = batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mysqlDB.Status.SchemaMigrationJob.Phase).To(Equal(migrationJobPhaseFailed))
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.List(ctx, jobs, client.InNamespace("mysql-operator"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+
+		// Forcing the same version again launches another Job
+		mysqlDB.Annotations = map[string]string{mysqlDBForceVersionAnnotation: "1"}
+		Expect(reconciler.Update(ctx, mysqlDB)).To(Succeed())
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mysqlDB.Status.SchemaMigrationJob.Name).NotTo(Equal(forced))
+		Expect(reconciler.List(ctx, jobs, client.InNamespace("mysql-operator"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(2))
+	})
+
+	It("Should remove the annotation after the Job forcing the version fails", func() {
+		mysqlDB.Annotations = map[string]string{mysqlDBForceVersionAnnotation: "1"}
+		Expect(reconciler.Update(ctx, mysqlDB)).To(Succeed())
+		_, err := reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "mysql-operator", Name: mysqlDB.Status.SchemaMigrationJob.Name}, job)).To(Succeed())
+		job.Status = batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		_, err = reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &mysqlv1alpha1.MySQLDB{}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(mysqlDB), updated)).To(Succeed())
+		Expect(updated.Annotations).NotTo(HaveKey(mysqlDBForceVersionAnnotation))
+		Expect(updated.Status.SchemaMigrationJob.Phase).To(Equal(migrationJobPhaseFailed))
+		Expect(updated.Status.SchemaMigrationJob.ForcedVersion).To(Equal("1"))
+	})
+
+	It("Should name a new Job for a new generation, forced version or ConfigMap", func() {
+		name, err := reconciler.migrationJobName(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(HavePrefix("sales-migrate-"))
+		Expect(len(name)).To(BeNumerically("<=", 63))
+
+		names := map[string]bool{name: true}
+		mysqlDB.Generation = 3
+		name, err = reconciler.migrationJobName(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		names[name] = true
+
+		mysqlDB.Annotations = map[string]string{mysqlDBForceVersionAnnotation: "1"}
+		name, err = reconciler.migrationJobName(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		names[name] = true
+
+		configMap := &corev1.ConfigMap{}
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "sales"}, configMap)).To(Succeed())
+		configMap.Data = map[string]string{"1_create.up.sql": "CREATE TABLE t (id int)"}
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		name, err = reconciler.migrationJobName(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		names[name] = true
+		Expect(names).To(HaveLen(4))
+	})
+
+	It("Should name a new Job when only the migration files in git change", func() {
+		dir := GinkgoT().TempDir()
+		repo, err := git.PlainInit(dir, false)
+		Expect(err).NotTo(HaveOccurred())
+		worktree, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		commit := func(name, data string) {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600)).To(Succeed())
+			_, err := worktree.Add(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = worktree.Commit("add "+name, &git.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		commit("1_create.up.sql", "CREATE TABLE t (id int)")
+
+		mysqlDB.Spec.SchemaMigrationFromConfigMap = nil
+		mysqlDB.Spec.SchemaMigrationFromGit = &mysqlv1alpha1.GitConfig{URL: dir}
+		name, err := reconciler.migrationJobName(ctx, mysqlDB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.migrationJobName(ctx, mysqlDB)).To(Equal(name))
+
+		commit("2_alter.up.sql", "ALTER TABLE t ADD COLUMN name varchar(32)")
+		Expect(reconciler.migrationJobName(ctx, mysqlDB)).NotTo(Equal(name))
+	})
+
+	It("Should fail without the migration Jobs enabled in the operator", func() {
+		reconciler.MigrationJob = nil
+		_, err := reconciler.reconcileMigrationJob(ctx, mysqlDB)
+		Expect(err).To(MatchError(ContainSubstring("--migration-job-image")))
+	})
+})
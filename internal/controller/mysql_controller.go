@@ -23,8 +23,10 @@ import (
 	"time"
 
 	. "github.com/go-sql-driver/mysql"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -35,7 +37,13 @@ import (
 	secret "github.com/nakamasato/mysql-operator/internal/secret"
 )
 
-const mysqlFinalizer = "mysql.nakamasato.com/finalizer"
+const (
+	mysqlFinalizer                      = "mysql.nakamasato.com/finalizer"
+	mysqlEventReasonConnected           = "Connected"
+	mysqlEventReasonConnectionFailed    = "ConnectionFailed"
+	mysqlEventReasonConnectionLost      = "ConnectionLost"
+	mysqlEventReasonConnectionRecovered = "ConnectionRecovered"
+)
 
 // MySQLReconciler reconciles a MySQL object
 type MySQLReconciler struct {
@@ -44,6 +52,7 @@ type MySQLReconciler struct {
 	MySQLClients    mysqlinternal.MySQLClients
 	MySQLDriverName string
 	SecretManagers  map[string]secret.SecretManager
+	Recorder        record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqls,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +61,7 @@ type MySQLReconciler struct {
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlusers,verbs=list;
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqldbs,verbs=list;
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -116,6 +126,11 @@ func (r *MySQLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	// Update MySQLClients
 	retry, err := r.UpdateMySQLClients(ctx, mysql)
 	if err != nil {
+		if mysql.Status.Connected {
+			r.Recorder.Eventf(mysql, corev1.EventTypeWarning, mysqlEventReasonConnectionLost, "Lost connection to %s:%d: %v", mysql.Spec.Host, mysql.Spec.Port, err)
+		} else {
+			r.Recorder.Eventf(mysql, corev1.EventTypeWarning, mysqlEventReasonConnectionFailed, "Failed to connect to %s:%d: %v", mysql.Spec.Host, mysql.Spec.Port, err)
+		}
 		mysql.Status.Connected = false
 		mysql.Status.Reason = err.Error()
 		if err := r.Status().Update(ctx, mysql); err != nil {
@@ -128,6 +143,14 @@ func (r *MySQLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	}
 
 	connected, reason := true, "Ping succeded and updated MySQLClients"
+	if !mysql.Status.Connected {
+		// Reason is only set after a connection attempt
+		if mysql.Status.Reason != "" {
+			r.Recorder.Eventf(mysql, corev1.EventTypeNormal, mysqlEventReasonConnectionRecovered, "Recovered connection to %s:%d", mysql.Spec.Host, mysql.Spec.Port)
+		} else {
+			r.Recorder.Eventf(mysql, corev1.EventTypeNormal, mysqlEventReasonConnected, "Connected to %s:%d", mysql.Spec.Host, mysql.Spec.Port)
+		}
+	}
 	if mysql.Status.Connected != connected || mysql.Status.Reason != reason {
 		mysql.Status.Connected = connected
 		mysql.Status.Reason = reason
@@ -187,39 +210,20 @@ func (r *MySQLReconciler) UpdateMySQLClients(ctx context.Context, mysql *mysqlv1
 		log.Info("Successfully added MySQL client", "mysql.Name", mysql.Name)
 	}
 
-	// open connection for each MySQLDB
-	mysqlDBList := &mysqlv1alpha1.MySQLDBList{}
-	err = r.List(ctx, mysqlDBList, client.MatchingFields{"spec.mysqlName": mysql.Name})
-	if err != nil {
-		return true, err
-	}
-	for _, mysqlDB := range mysqlDBList.Items {
-		if mysqlDB.Status.Phase != "Ready" {
-			log.Info("mysqlDB is not ready", "mysqlDB", mysqlDB.Name, "mysqlDB.Status", mysqlDB.Status)
-			return true, nil
-		}
-		if _, err := r.MySQLClients.GetClient(mysqlDB.GetKey()); err != nil {
-			cfg.DBName = mysqlDB.Spec.DBName
-			db, err := sql.Open(r.MySQLDriverName, cfg.FormatDSN())
-			if err != nil {
-				return true, err
-			}
-			err = db.PingContext(ctx)
-			if err != nil {
-				return true, err
-			}
-			r.MySQLClients[mysqlDB.GetKey()] = db
-			log.Info("Successfully added MySQL client", "mysqlDB.Name", mysqlDB.Name)
-		}
-	}
 	return false, nil
 }
 
 // If GcpSecretName is set, get password from GCP secret manager
 // Otherwise user MySQL.Spec.AdminPassword
 func (r *MySQLReconciler) getMySQLConfig(ctx context.Context, mysql *mysqlv1alpha1.MySQL) (Config, error) {
+	return getMySQLConfig(ctx, r.SecretManagers, mysql)
+}
+
+// getMySQLConfig returns the config to connect to the MySQL with the admin
+// user and password read from the secret managers
+func getMySQLConfig(ctx context.Context, secretManagers map[string]secret.SecretManager, mysql *mysqlv1alpha1.MySQL) (Config, error) {
 	log := log.FromContext(ctx)
-	secretManager, ok := r.SecretManagers[mysql.Spec.AdminPassword.Type]
+	secretManager, ok := secretManagers[mysql.Spec.AdminPassword.Type]
 	if !ok {
 		return Config{}, fmt.Errorf("the specified SecretManager type (%s) doesn't exist", mysql.Spec.AdminPassword.Type)
 	}
@@ -228,7 +232,7 @@ func (r *MySQLReconciler) getMySQLConfig(ctx context.Context, mysql *mysqlv1alph
 		log.Error(err, "failed to get secret from secret manager", "secret", mysql.Spec.AdminPassword.Name)
 		return Config{}, err
 	}
-	secretManager, ok = r.SecretManagers[mysql.Spec.AdminUser.Type]
+	secretManager, ok = secretManagers[mysql.Spec.AdminUser.Type]
 	if !ok {
 		return Config{}, fmt.Errorf("the specified SecretManager type (%s) doesn't exist", mysql.Spec.AdminUser.Type)
 	}
@@ -52,6 +52,7 @@ var _ = Describe("MySQL controller", func() {
 			MySQLClients:    mySQLClients,
 			MySQLDriverName: "testdbdriver",
 			SecretManagers:  map[string]secret.SecretManager{"raw": secret.RawSecretManager{}},
+			Recorder:        k8sManager.GetEventRecorderFor("mysql-controller"),
 		}
 		err = ctrl.NewControllerManagedBy(k8sManager).
 			For(&mysqlv1alpha1.MySQL{}).
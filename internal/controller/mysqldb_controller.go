@@ -19,29 +19,72 @@ package controllers
 import (
 	"context"
 	"database/sql"
+	stderrors "errors"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/github"
 	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	"github.com/nakamasato/mysql-operator/internal/migration"
 	mysqlinternal "github.com/nakamasato/mysql-operator/internal/mysql"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	mysqlDBFinalizer                   = "mysqldb.nakamasato.com/finalizer"
+	mysqlDBForceVersionAnnotation      = "mysqldb.nakamasato.com/force-migration-version"
 	mysqlDBPhaseNotReady               = "NotReady"
 	mysqlDBReasonMySQLFetchFailed      = "Failed to fetch MySQL"
 	mysqlDBReasonMySQLConnectionFailed = "Failed to connect to mysql"
 	mysqlDBPhaseReady                  = "Ready"
 	mysqlDBReasonCompleted             = "Database successfully created"
+	mysqlDBReasonAdopted               = "Database successfully adopted"
+	mysqlDBReasonAlreadyExists         = "Database already exists"
+	mysqlDBReasonPlanned               = "Statements are planned but not executed"
+	mysqlDBReasonFailedToUpdate        = "Failed to update properties or quotas"
+	mysqlDBPhasePlanned                = "Planned"
+	mysqlDBEventReasonConnectionFailed = "ConnectionFailed"
+	mysqlDBEventReasonCreated          = "CreatedDatabase"
+	mysqlDBEventReasonFailedToCreate   = "FailedToCreateDatabase"
+	mysqlDBEventReasonAdopted          = "AdoptedDatabase"
+	mysqlDBEventReasonDropped          = "DroppedDatabase"
+	mysqlDBEventReasonMigrated         = "AppliedMigration"
+	mysqlDBEventReasonFailedToMigrate  = "FailedToMigrate"
+	mysqlDBEventReasonForcedVersion    = "ForcedMigrationVersion"
+	mysqlDBEventReasonDownNotAllowed   = "MigrationDownNotAllowed"
+	mysqlDBEventReasonPlanned          = "Planned"
+	mysqlDBEventReasonUpdated          = "UpdatedDatabase"
+	mysqlDBEventReasonFailedToUpdate   = "FailedToUpdateDatabase"
+)
+
+var (
+	// e.g. "replication_num" = "3" in SHOW CREATE DATABASE
+	databasePropertyRegexp = regexp.MustCompile(`"([^"]+)"\s*=\s*"([^"]*)"`)
+	// e.g. 100GB, 1.5 T, or 1024.000 TB in SHOW DATA
+	dataSizeRegexp = regexp.MustCompile(`(?i)^([0-9]+(?:\.[0-9]+)?)\s*([KMGTP]?)B?$`)
 )
 
 // MySQLDBReconciler reconciles a MySQLDB object
@@ -49,11 +92,28 @@ type MySQLDBReconciler struct {
 	client.Client
 	Scheme       *runtime.Scheme
 	MySQLClients mysqlinternal.MySQLClients
+	Recorder     record.EventRecorder
+	// PlanOnly plans statements for all databases without executing them
+	PlanOnly bool
+	// MigrationJob enables running schema migrations in Jobs
+	MigrationJob *MigrationJobConfig
+	// Clientset reads the logs of the migration Jobs
+	Clientset kubernetes.Interface
+	// BucketAmbientCredentials allows bucket sources without a Secret to use
+	// the credentials of the operator, e.g. IAM roles for service accounts
+	BucketAmbientCredentials bool
 }
 
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqldbs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqldbs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqldbs/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 // Reconcile function is responsible for managing MySQL database.
 // Create database if not exists in the target MySQL and drop it if
@@ -74,6 +134,12 @@ func (r *MySQLDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	// Orphan the database without looking up MySQL, which might be already gone
+	if !mysqlDB.GetDeletionTimestamp().IsZero() && mysqlDB.Spec.DeletionPolicy == mysqlv1alpha1.DeletionPolicyOrphan {
+		log.Info("[Finalize] Orphan database", "database", mysqlDB.GetQualifiedName())
+		return ctrl.Result{}, r.detachMySQLDB(ctx, mysqlDB)
+	}
+
 	// 2. Fetch MySQL
 	mysql := &mysqlv1alpha1.MySQL{}
 	if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: mysqlDB.Spec.ClusterName}, mysql); err != nil {
@@ -86,10 +152,33 @@ func (r *MySQLDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	// Databases created by an operator that didn't record the origin are still
+	// dropped after deletion. It set the reason only after creating a database.
+	if mysqlDB.Status.Origin == "" && mysqlDB.Status.Reason == mysqlDBReasonCompleted {
+		mysqlDB.Status.Origin = mysqlv1alpha1.OriginCreated
+		if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+			log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+			return ctrl.Result{RequeueAfter: time.Second}, nil
+		}
+	}
+
+	// Keep the database in MySQL unless deletionPolicy is Delete
+	planOnly := r.PlanOnly || mysqlDB.Spec.PlanOnly
+	if !mysqlDB.GetDeletionTimestamp().IsZero() {
+		if deletionPolicy := mysqlDB.GetDeletionPolicy(mysql); deletionPolicy != mysqlv1alpha1.DeletionPolicyDelete || planOnly {
+			log.Info("[Finalize] Keep database", "database", mysqlDB.GetQualifiedName(), "deletionPolicy", deletionPolicy, "planOnly", planOnly)
+			if deletionPolicy == mysqlv1alpha1.DeletionPolicyDelete {
+				r.planFinalizeMySQLDB(ctx, mysqlDB)
+			}
+			return ctrl.Result{}, r.detachMySQLDB(ctx, mysqlDB)
+		}
+	}
+
 	// 3. Get mysqlClient without specifying database
 	mysqlClient, err := r.MySQLClients.GetClient(mysql.GetKey())
 	if err != nil {
 		log.Error(err, "Failed to get MySQL client", "key", mysqlDB.GetKey())
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonConnectionFailed, "Failed to connect to cluster %s: %v", mysqlDB.Spec.ClusterName, err)
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
@@ -99,12 +188,7 @@ func (r *MySQLDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			if err := r.finalizeMySQLDB(ctx, mysqlClient, mysqlDB); err != nil {
 				return ctrl.Result{}, err
 			}
-			if controllerutil.RemoveFinalizer(mysqlDB, mysqlDBFinalizer) {
-				if err := r.Update(ctx, mysqlDB); err != nil {
-					return ctrl.Result{}, err
-				}
-			}
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.detachMySQLDB(ctx, mysqlDB)
 		}
 		return ctrl.Result{}, nil
 	}
@@ -117,10 +201,39 @@ func (r *MySQLDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
+	// Only plan the statements without executing them
+	if planOnly {
+		return r.planMySQLDB(ctx, mysqlClient, mysqlDB)
+	}
+	if len(mysqlDB.Status.Plan) > 0 {
+		mysqlDB.Status.Plan = nil
+		if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+			log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+			return ctrl.Result{RequeueAfter: time.Second}, nil
+		}
+	}
+
 	// 6. Create database if not exists
-	res, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", mysqlDB.Spec.DBName))
+	if mysqlDB.Status.Origin == "" {
+		exists, err := databaseExists(ctx, mysqlClient, mysqlDB)
+		if err != nil {
+			log.Error(err, "[MySQL] Failed to check database", "database", mysqlDB.GetQualifiedName())
+			return ctrl.Result{}, err
+		}
+		// Record the origin before creating the database, so that the next reconcile
+		// doesn't adopt the database if the status can't be updated after creating it
+		if !exists {
+			mysqlDB.Status.Origin = mysqlv1alpha1.OriginCreated
+			if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+				log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+		}
+	}
+	res, err := mysqlClient.ExecContext(ctx, createDatabaseStatement(mysqlDB))
 	if err != nil {
-		log.Error(err, "[MySQL] Failed to create MySQL database.", "mysql", mysql.Name, "database", mysqlDB.Spec.DBName)
+		log.Error(err, "[MySQL] Failed to create MySQL database.", "mysql", mysql.Name, "database", mysqlDB.GetQualifiedName())
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToCreate, "Failed to create database %s: %v", mysqlDB.GetQualifiedName(), err)
 		mysqlDB.Status.Phase = mysqlDBPhaseNotReady
 		mysqlDB.Status.Reason = err.Error()
 		if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
@@ -137,79 +250,726 @@ func (r *MySQLDBReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if rows > 0 {
 		mysqlDB.Status.Phase = mysqlDBPhaseReady
 		mysqlDB.Status.Reason = mysqlDBReasonCompleted
+		mysqlDB.Status.Origin = mysqlv1alpha1.OriginCreated
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonCreated, "Created database %s", mysqlDB.GetQualifiedName())
 		if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
-			log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.Spec.DBName)
+			log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+			return ctrl.Result{RequeueAfter: time.Second}, nil
+		}
+	} else if mysqlDB.Status.Origin == "" {
+		// The database exists but was neither created nor adopted by the operator
+		if mysqlDB.Spec.AdoptionPolicy == mysqlv1alpha1.AdoptionPolicyFailIfExists {
+			log.Info("database already exists", "database", mysqlDB.GetQualifiedName())
+			mysqlDB.Status.Phase = mysqlDBPhaseNotReady
+			mysqlDB.Status.Reason = mysqlDBReasonAlreadyExists
+			if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+				log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		log.Info("adopted existing database", "database", mysqlDB.GetQualifiedName(), "adoptionPolicy", mysqlDB.Spec.AdoptionPolicy)
+		mysqlDB.Status.Phase = mysqlDBPhaseReady
+		mysqlDB.Status.Reason = mysqlDBReasonAdopted
+		mysqlDB.Status.Origin = mysqlv1alpha1.OriginAdopted
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonAdopted, "Adopted existing database %s", mysqlDB.GetQualifiedName())
+		if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+			log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
 			return ctrl.Result{RequeueAfter: time.Second}, nil
 		}
 	} else {
-		log.Info("database already exists", "database", mysqlDB.Spec.DBName)
+		log.Info("database already exists", "database", mysqlDB.GetQualifiedName())
 	}
 
-	// 7. Get MySQL client for database
-	mysqlClient, err = r.MySQLClients.GetClient(mysqlDB.GetKey())
-	if err != nil {
-		log.Error(err, "Failed to get MySQL Client", "key", mysqlDB.GetKey())
-		return ctrl.Result{}, err
+	// Apply properties and quotas
+	if !mysqlDB.IsReadOnly() {
+		if err := r.updateDatabase(ctx, mysqlClient, mysqlDB); err != nil {
+			log.Error(err, "[MySQL] Failed to update database", "database", mysqlDB.GetQualifiedName())
+			mysqlDB.Status.Phase = mysqlDBPhaseNotReady
+			mysqlDB.Status.Reason = mysqlDBReasonFailedToUpdate
+			if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+				log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if mysqlDB.Status.Phase != mysqlDBPhaseReady {
+			mysqlDB.Status.Phase = mysqlDBPhaseReady
+			mysqlDB.Status.Reason = mysqlDBReasonCompleted
+			if mysqlDB.Status.Origin == mysqlv1alpha1.OriginAdopted {
+				mysqlDB.Status.Reason = mysqlDBReasonAdopted
+			}
+			if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+				log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+		}
 	}
 
-	// 6. Migrate database
-	if mysqlDB.Spec.SchemaMigrationFromGitHub == nil {
+	// 7. Migrate database
+	if !mysqlDB.HasSchemaMigration() {
+		return ctrl.Result{}, nil
+	}
+	if mysqlDB.IsReadOnly() {
+		log.Info("skip schema migration for read-only database", "database", mysqlDB.GetQualifiedName())
+		return ctrl.Result{}, nil
+	}
+	if mysqlDB.Spec.Catalog != "" {
+		// The migrations table is an OLAP table, which only the internal catalog has
+		log.Info("skip schema migration for database in an external catalog", "database", mysqlDB.GetQualifiedName())
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToMigrate, "Schema migrations are not supported for database %s in external catalog %s", mysqlDB.GetQualifiedName(), mysqlDB.Spec.Catalog)
 		return ctrl.Result{}, nil
 	}
-	driver, err := migratemysql.WithInstance( // initialize db driver instance
-		mysqlClient,
-		&migratemysql.Config{DatabaseName: mysqlDB.Spec.DBName},
-	)
+	if mysqlDB.RunsSchemaMigrationInJob() {
+		return r.reconcileMigrationJob(ctx, mysqlDB)
+	}
+	return ctrl.Result{}, r.runSchemaMigration(ctx, mysqlDB, mysql, mysqlClient)
+}
+
+// runSchemaMigration migrates the schema of the database with the cluster-level
+// client and updates the version in the status
+func (r *MySQLDBReconciler) runSchemaMigration(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB, mysql *mysqlv1alpha1.MySQL, mysqlClient *sql.DB) error {
+	log := log.FromContext(ctx)
+	flavor, err := migrationFlavor(ctx, mysql, mysqlClient)
 	if err != nil {
-		log.Error(err, "failed to create migratemysql.WithInstance")
-		return ctrl.Result{}, err
+		log.Error(err, "failed to detect dialect")
+		return err
+	}
+	driver, err := migration.WithStarRocksInstance(ctx, mysqlClient, &migration.StarRocksConfig{ // initialize db driver instance
+		DatabaseName: mysqlDB.GetQualifiedName(),
+		Flavor:       flavor,
+		Locker:       migration.NewLeaseLocker(r.Client, mysqlDB.Namespace, schemaMigrationLeaseName(mysqlDB)),
+	})
+	if err != nil {
+		log.Error(err, "failed to create migration.WithStarRocksInstance")
+		return err
 	}
+	defer driver.Close()
 
-	m, err := migrate.NewWithDatabaseInstance( // initialize Migrate with db driver instance
-		// "github://nakamasato/mysql-operator/config/sample-migrations#enable-to-migrate-schema-with-migrate", // Currently only support GitHub source
-		mysqlDB.Spec.SchemaMigrationFromGitHub.GetSourceUrl(),
-		mysqlDB.Spec.DBName,
-		driver,
-	)
+	sourceDriver, sourceName, revision, err := r.openSource(ctx, mysqlDB)
 	if err != nil {
-		log.Error(err, "failed to initialize NewWithDatabaseInstance")
-		return ctrl.Result{}, err
+		log.Error(err, "failed to open source")
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToMigrate, "Failed to read migrations of database %s: %v", mysqlDB.GetQualifiedName(), err)
+		return err
 	}
-	err = m.Up() // TODO: enable to specify what to do.
+	m, err := migrate.NewWithInstance(sourceName, sourceDriver, mysqlDB.Spec.DBName, driver) // initialize Migrate with db driver instance
 	if err != nil {
-		if err.Error() == "no change" {
-			log.Info("migrate no change")
-		} else {
-			log.Error(err, "failed to Up")
-			return ctrl.Result{}, err
+		log.Error(err, "failed to initialize Migrate")
+		return err
+	}
+	err = r.migrateSchema(ctx, mysqlDB, m, sourceDriver)
+	if stderrors.Is(err, errMigrationsModified) {
+		// Only a change in the source fixes it, which triggers another reconciliation
+		log.Error(err, "refuse to migrate")
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToMigrate, "Refused to migrate database %s: %v", mysqlDB.GetQualifiedName(), err)
+	} else if err != nil {
+		log.Error(err, "failed to migrate")
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToMigrate, "Failed to migrate database %s: %v", mysqlDB.GetQualifiedName(), err)
+	}
+
+	// Update the status even if the migration failed, e.g. to show that the database is dirty
+	version, dirty, verr := schemaVersion(m)
+	if verr != nil {
+		return verr
+	}
+	log.Info("migrate completed", "version", version, "dirty", dirty, "revision", revision)
+
+	mysqlDB.Status.SchemaMigration.Version = version
+	mysqlDB.Status.SchemaMigration.Dirty = dirty
+	mysqlDB.Status.SchemaMigrationRevision = revision
+	if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+		return serr
+	}
+
+	if stderrors.Is(err, errMigrationsModified) {
+		return nil
+	}
+	return err
+}
+
+// migrateSchema forces the version in the annotation if any, verifies the
+// applied migrations against the source, and migrates the schema to the target
+// version of the policy, or the latest version. The migrations are applied one
+// by one to report and record each step in the history.
+func (r *MySQLDBReconciler) migrateSchema(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB, m *migrate.Migrate, sourceDriver source.Driver) error {
+	log := log.FromContext(ctx)
+	name := mysqlDB.GetQualifiedName()
+
+	if value, ok := mysqlDB.Annotations[mysqlDBForceVersionAnnotation]; ok {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < database.NilVersion {
+			return fmt.Errorf("invalid version %q in annotation %s", value, mysqlDBForceVersionAnnotation)
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+		log.Info("forced migration version", "version", version)
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonForcedVersion, "Forced migration version %d of database %s", version, name)
+		// Remove the annotation not to force the version again
+		delete(mysqlDB.Annotations, mysqlDBForceVersionAnnotation)
+		if err := r.Update(ctx, mysqlDB); err != nil {
+			return err
+		}
+		forgetMigrationsAfter(mysqlDB, uint(max(version, 0)))
+	}
+
+	if err := verifyAppliedMigrations(mysqlDB, sourceDriver); err != nil {
+		return err
+	}
+
+	current, dirty, err := schemaVersion(m)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d: fix the database and set annotation %s to the last successful version", current, mysqlDBForceVersionAnnotation)
+	}
+
+	var target *uint
+	policy := mysqlDB.Spec.SchemaMigrationPolicy
+	if policy != nil {
+		target = policy.TargetVersion
+	}
+	if target != nil && current > *target {
+		if !policy.AllowDown {
+			log.Info("skip migrating down", "version", current, "targetVersion", *target)
+			r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonDownNotAllowed, "Database %s is at version %d ahead of target version %d: set allowDown to migrate down", name, current, *target)
+			return nil
+		}
+		for current > *target {
+			if err := m.Steps(-1); err != nil {
+				return err
+			}
+			reverted := current
+			if current, _, err = schemaVersion(m); err != nil {
+				return err
+			}
+			forgetMigrationsAfter(mysqlDB, current)
+			r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonMigrated, "Reverted migration version %d of database %s", reverted, name)
+		}
+		return nil
+	}
+
+	for target == nil || current < *target {
+		next, err := nextVersion(sourceDriver, current)
+		if stderrors.Is(err, os.ErrNotExist) {
+			if target != nil {
+				return fmt.Errorf("target version %d is not in the source", *target)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if target != nil && next > *target {
+			return fmt.Errorf("target version %d is not in the source", *target)
 		}
+		start := time.Now()
+		if err := m.Steps(1); err != nil {
+			return err
+		}
+		if err := recordAppliedMigration(mysqlDB, sourceDriver, next, start); err != nil {
+			return err
+		}
+		current = next
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonMigrated, "Applied migration version %d to database %s", current, name)
 	}
+	return nil
+}
 
+// schemaVersion returns the version of the schema, which is 0 before any migration
+func schemaVersion(m *migrate.Migrate) (uint, bool, error) {
 	version, dirty, err := m.Version()
+	if stderrors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// schemaMigrationLeaseName returns the name of the Lease that locks the
+// migrations of the database, shared by the reconciler and the migration Jobs
+func schemaMigrationLeaseName(mysqlDB *mysqlv1alpha1.MySQLDB) string {
+	h := fnv.New32a()
+	h.Write([]byte(mysqlDB.GetKey()))
+	return fmt.Sprintf("schema-migration-%08x", h.Sum32())
+}
+
+// openSource returns the source driver in the spec with its name for
+// migrate.NewWithInstance, and the commit SHA of the migrations for the git source
+func (r *MySQLDBReconciler) openSource(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) (source.Driver, string, string, error) {
+	if config := mysqlDB.Spec.SchemaMigrationFromConfigMap; config != nil {
+		configMaps := make([]corev1.ConfigMap, 0, len(config.Names))
+		for _, name := range config.Names {
+			configMap := corev1.ConfigMap{}
+			if err := r.Get(ctx, client.ObjectKey{Namespace: mysqlDB.Namespace, Name: name}, &configMap); err != nil {
+				return nil, "", "", err
+			}
+			configMaps = append(configMaps, configMap)
+		}
+		sourceDriver, err := migration.NewConfigMapSource(configMaps)
+		return sourceDriver, migration.ConfigMapSourceName, "", err
+	}
+	if config := mysqlDB.Spec.SchemaMigrationFromGit; config != nil {
+		repository, err := r.gitRepository(ctx, mysqlDB.Namespace, config)
+		if err != nil {
+			return nil, "", "", err
+		}
+		sourceDriver, revision, err := migration.NewGitSource(ctx, repository)
+		if err != nil {
+			return nil, "", "", err
+		}
+		log.FromContext(ctx).Info("resolved ref of git source", "url", config.URL, "ref", config.Ref, "revision", revision)
+		return sourceDriver, migration.GitSourceName, revision, nil
+	}
+	if config := mysqlDB.Spec.SchemaMigrationFromBucket; config != nil {
+		bucket, err := r.bucket(ctx, mysqlDB.Namespace, config)
+		if err != nil {
+			return nil, "", "", err
+		}
+		sourceDriver, err := migration.NewBucketSource(ctx, bucket)
+		return sourceDriver, migration.BucketSourceName, "", err
+	}
+	config := mysqlDB.Spec.SchemaMigrationFromGitHub
+	if config.TokenSecretRef != nil {
+		token, err := r.gitHubToken(ctx, mysqlDB.Namespace, config)
+		if err != nil {
+			return nil, "", "", err
+		}
+		// The URL has the token, so it must not be in logs and errors
+		sourceDriver, err := source.Open(config.GetSourceUrlWithToken(token))
+		return sourceDriver, "github", "", migration.RedactToken(err, token)
+	}
+	// e.g. github://nakamasato/mysql-operator/config/sample-migrations#enable-to-migrate-schema-with-migrate
+	sourceDriver, err := source.Open(config.GetSourceUrl())
+	return sourceDriver, "github", "", err
+}
+
+// migrationFlavor returns the flavor of the cluster for the migrations table,
+// which is detected with a query only if the MySQL doesn't set it
+func migrationFlavor(ctx context.Context, mysql *mysqlv1alpha1.MySQL, mysqlClient *sql.DB) (mysqlv1alpha1.Flavor, error) {
+	if mysql.Spec.Flavor != "" {
+		return mysql.Spec.Flavor, nil
+	}
+	dialect, err := detectDialect(ctx, mysqlClient)
 	if err != nil {
-		return ctrl.Result{}, err
+		return "", err
 	}
-	log.Info("migrate completed", "version", version, "dirty", dirty)
+	if dialect == DialectDoris {
+		return mysqlv1alpha1.FlavorDoris, nil
+	}
+	return mysqlv1alpha1.FlavorStarRocks, nil
+}
 
-	mysqlDB.Status.SchemaMigration.Version = version
-	mysqlDB.Status.SchemaMigration.Dirty = dirty
-	err = r.Status().Update(ctx, mysqlDB)
+// sourceRevision returns the revision of the migration files in the source
+// without downloading them: the resourceVersions of the ConfigMaps, the
+// commit SHA of the git ref, the ETags of the objects in the bucket, or the
+// last commit of the path on GitHub.
+func (r *MySQLDBReconciler) sourceRevision(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) (string, error) {
+	if config := mysqlDB.Spec.SchemaMigrationFromConfigMap; config != nil {
+		resourceVersions := make([]string, 0, len(config.Names))
+		for _, name := range config.Names {
+			configMap := corev1.ConfigMap{}
+			if err := r.Get(ctx, client.ObjectKey{Namespace: mysqlDB.Namespace, Name: name}, &configMap); err != nil {
+				return "", err
+			}
+			resourceVersions = append(resourceVersions, configMap.ResourceVersion)
+		}
+		return strings.Join(resourceVersions, "/"), nil
+	}
+	if config := mysqlDB.Spec.SchemaMigrationFromGit; config != nil {
+		repository, err := r.gitRepository(ctx, mysqlDB.Namespace, config)
+		if err != nil {
+			return "", err
+		}
+		return migration.ResolveGitRef(ctx, repository)
+	}
+	if config := mysqlDB.Spec.SchemaMigrationFromBucket; config != nil {
+		bucket, err := r.bucket(ctx, mysqlDB.Namespace, config)
+		if err != nil {
+			return "", err
+		}
+		return migration.BucketRevision(ctx, bucket)
+	}
+	if config := mysqlDB.Spec.SchemaMigrationFromGitHub; config != nil {
+		token := ""
+		if config.TokenSecretRef != nil {
+			var err error
+			if token, err = r.gitHubToken(ctx, mysqlDB.Namespace, config); err != nil {
+				return "", err
+			}
+		}
+		return migration.GitHubRevision(ctx, config.Owner, config.Repo, config.Path, config.Ref, token)
+	}
+	return "", nil
+}
+
+// gitRepository returns the git repository with the credentials in the Secret
+func (r *MySQLDBReconciler) gitRepository(ctx context.Context, namespace string, config *mysqlv1alpha1.GitConfig) (migration.GitRepository, error) {
+	repository := migration.GitRepository{URL: config.URL, Ref: config.Ref, Path: config.Path}
+	if config.SecretName != "" {
+		secret := corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.SecretName}, &secret); err != nil {
+			return repository, err
+		}
+		repository.Credentials = secret.Data
+	}
+	return repository, nil
+}
+
+// bucket returns the bucket with the credentials in the Secret. Without the
+// Secret, the credentials of the operator are used only if it allows them.
+func (r *MySQLDBReconciler) bucket(ctx context.Context, namespace string, config *mysqlv1alpha1.BucketConfig) (migration.Bucket, error) {
+	bucket := migration.Bucket{Endpoint: config.Endpoint, Region: config.Region, Name: config.Bucket, Prefix: config.Prefix, AmbientCredentials: r.BucketAmbientCredentials}
+	if config.SecretName == "" && !r.BucketAmbientCredentials {
+		return bucket, fmt.Errorf("secretName is required for bucket %s: the operator doesn't allow its own credentials without --bucket-ambient-credentials", config.Bucket)
+	}
+	if config.SecretName != "" {
+		secret := corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.SecretName}, &secret); err != nil {
+			return bucket, err
+		}
+		bucket.Credentials = secret.Data
+	}
+	return bucket, nil
+}
+
+// gitHubToken returns the personal access token in the Secret, or an
+// installation token of the GitHub App whose private key is in the Secret
+func (r *MySQLDBReconciler) gitHubToken(ctx context.Context, namespace string, config *mysqlv1alpha1.GitHubConfig) (string, error) {
+	secret := corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.TokenSecretRef.Name}, &secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[config.TokenSecretRef.Key]
+	if !ok || len(value) == 0 {
+		return "", fmt.Errorf("key %s is not found in Secret %s", config.TokenSecretRef.Key, config.TokenSecretRef.Name)
+	}
+	if config.AppID == 0 {
+		return strings.TrimSpace(string(value)), nil
+	}
+	return migration.GitHubAppToken(ctx, config.AppID, config.InstallationID, value)
+}
+
+// planMySQLDB publishes the statements to create the database to the status
+// and an Event without executing them. Schema migrations are not planned.
+func (r *MySQLDBReconciler) planMySQLDB(ctx context.Context, mysqlClient *sql.DB, mysqlDB *mysqlv1alpha1.MySQLDB) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	plan := []string{}
+
+	exists, err := databaseExists(ctx, mysqlClient, mysqlDB)
 	if err != nil {
+		log.Error(err, "[Plan] Failed to check database", "database", mysqlDB.GetQualifiedName())
 		return ctrl.Result{}, err
 	}
+	if !exists {
+		plan = append(plan, createDatabaseStatement(mysqlDB))
+		plan = append(plan, quotaStatements(mysqlDB)...)
+	} else if !mysqlDB.IsReadOnly() {
+		statements, err := alterDatabaseStatements(ctx, mysqlClient, mysqlDB)
+		if err != nil {
+			log.Error(err, "[Plan] Failed to check properties and quotas", "database", mysqlDB.GetQualifiedName())
+			return ctrl.Result{}, err
+		}
+		plan = append(plan, statements...)
+	}
+	log.Info("[Plan] Planned statements", "database", mysqlDB.GetQualifiedName(), "statements", len(plan))
+	r.Recorder.Event(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonPlanned, formatPlan(plan))
 
+	mysqlDB.Status.Phase = mysqlDBPhasePlanned
+	mysqlDB.Status.Reason = mysqlDBReasonPlanned
+	mysqlDB.Status.Plan = plan
+	if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+		log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-// finalizeMySQLDB drops MySQL database
+func createDatabaseStatement(mysqlDB *mysqlv1alpha1.MySQLDB) string {
+	statement := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", mysqlDB.GetQualifiedName())
+	properties := map[string]string{}
+	for key, value := range mysqlDB.Spec.Properties {
+		properties[key] = value
+	}
+	if mysqlDB.Spec.Location != "" {
+		properties["location"] = mysqlDB.Spec.Location
+	}
+	if len(properties) > 0 {
+		statement += fmt.Sprintf(" PROPERTIES (%s)", formatProperties(properties))
+	}
+	return statement
+}
+
+// databaseExists returns true if the database exists in its catalog
+func databaseExists(ctx context.Context, mysqlClient *sql.DB, mysqlDB *mysqlv1alpha1.MySQLDB) (bool, error) {
+	if mysqlDB.Spec.Catalog == "" {
+		var name string
+		err := mysqlClient.QueryRowContext(ctx, fmt.Sprintf("SELECT SCHEMA_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = '%s'", mysqlDB.Spec.DBName)).Scan(&name)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	// information_schema only has the databases in the internal catalog
+	rows, err := mysqlClient.QueryContext(ctx, fmt.Sprintf("SHOW DATABASES FROM %s", mysqlDB.Spec.Catalog))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == mysqlDB.Spec.DBName {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// formatProperties returns the properties sorted by key, e.g. "replication_num" = "3", "storage_volume" = "s3"
+func formatProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%q = %q", key, properties[key]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// quotaStatements returns the statements to set the quotas in the spec
+func quotaStatements(mysqlDB *mysqlv1alpha1.MySQLDB) []string {
+	statements := []string{}
+	if mysqlDB.Spec.DataQuota != "" {
+		statements = append(statements, fmt.Sprintf("ALTER DATABASE %s SET DATA QUOTA %s", mysqlDB.GetQualifiedName(), mysqlDB.Spec.DataQuota))
+	}
+	if mysqlDB.Spec.ReplicaQuota != nil {
+		statements = append(statements, fmt.Sprintf("ALTER DATABASE %s SET REPLICA QUOTA %d", mysqlDB.GetQualifiedName(), *mysqlDB.Spec.ReplicaQuota))
+	}
+	return statements
+}
+
+// updateDatabase applies the properties and quotas in the spec that differ from the database
+func (r *MySQLDBReconciler) updateDatabase(ctx context.Context, mysqlClient *sql.DB, mysqlDB *mysqlv1alpha1.MySQLDB) error {
+	log := log.FromContext(ctx)
+	statements, err := alterDatabaseStatements(ctx, mysqlClient, mysqlDB)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := mysqlClient.ExecContext(ctx, statement); err != nil {
+			r.Recorder.Eventf(mysqlDB, corev1.EventTypeWarning, mysqlDBEventReasonFailedToUpdate, "Failed to update database %s: %v", mysqlDB.GetQualifiedName(), err)
+			return err
+		}
+		log.Info("[MySQL] Updated database", "database", mysqlDB.GetQualifiedName(), "statement", statement)
+		r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonUpdated, "Updated database %s: %s", mysqlDB.GetQualifiedName(), statement)
+	}
+	return nil
+}
+
+// alterDatabaseStatements compares the properties in the spec with SHOW CREATE DATABASE
+// and the quotas with SHOW DATA, and returns the statements to apply the differences
+func alterDatabaseStatements(ctx context.Context, mysqlClient *sql.DB, mysqlDB *mysqlv1alpha1.MySQLDB) ([]string, error) {
+	statements := []string{}
+
+	if len(mysqlDB.Spec.Properties) > 0 {
+		var name, createStatement string
+		if err := mysqlClient.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE DATABASE %s", mysqlDB.GetQualifiedName())).Scan(&name, &createStatement); err != nil {
+			return nil, err
+		}
+		existing := parseDatabaseProperties(createStatement)
+		changed := map[string]string{}
+		for key, value := range mysqlDB.Spec.Properties {
+			if existing[key] != value {
+				changed[key] = value
+			}
+		}
+		if len(changed) > 0 {
+			statements = append(statements, fmt.Sprintf("ALTER DATABASE %s SET PROPERTIES (%s)", mysqlDB.GetQualifiedName(), formatProperties(changed)))
+		}
+	}
+
+	if mysqlDB.Spec.DataQuota == "" && mysqlDB.Spec.ReplicaQuota == nil {
+		return statements, nil
+	}
+	dataQuota, replicaQuota, err := showQuotas(ctx, mysqlClient, mysqlDB.GetQualifiedName())
+	if err != nil {
+		return nil, err
+	}
+	if mysqlDB.Spec.DataQuota != "" {
+		desired, err := parseDataSize(mysqlDB.Spec.DataQuota)
+		if err != nil {
+			return nil, err
+		}
+		if !sameDataSize(desired, dataQuota) {
+			statements = append(statements, fmt.Sprintf("ALTER DATABASE %s SET DATA QUOTA %s", mysqlDB.GetQualifiedName(), mysqlDB.Spec.DataQuota))
+		}
+	}
+	if mysqlDB.Spec.ReplicaQuota != nil && *mysqlDB.Spec.ReplicaQuota != replicaQuota {
+		statements = append(statements, fmt.Sprintf("ALTER DATABASE %s SET REPLICA QUOTA %d", mysqlDB.GetQualifiedName(), *mysqlDB.Spec.ReplicaQuota))
+	}
+	return statements, nil
+}
+
+// parseDatabaseProperties returns the properties in the output of SHOW CREATE DATABASE
+func parseDatabaseProperties(createStatement string) map[string]string {
+	properties := map[string]string{}
+	for _, m := range databasePropertyRegexp.FindAllStringSubmatch(createStatement, -1) {
+		properties[m[1]] = m[2]
+	}
+	return properties
+}
+
+// showQuotas returns the data quota in bytes and the replica quota from the
+// Quota row of SHOW DATA, which shows the data of the current database.
+// FROM of SHOW DATA takes a table in Doris, so the database is switched with
+// USE, and the connection is discarded not to switch the others of the pool.
+func showQuotas(ctx context.Context, mysqlClient *sql.DB, dbName string) (float64, int64, error) {
+	conn, err := mysqlClient.Conn(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer discardConn(conn)
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE %s", dbName)); err != nil {
+		return 0, 0, err
+	}
+	rows, err := conn.QueryContext(ctx, "SHOW DATA")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(columns) < 3 { // TableName, Size, ReplicaCount
+		return 0, 0, fmt.Errorf("unexpected number of columns of SHOW DATA: %d", len(columns))
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		scanArgs := make([]interface{}, len(columns))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return 0, 0, err
+		}
+		if values[0].String != "Quota" {
+			continue
+		}
+		dataQuota, err := parseDataSize(values[1].String)
+		if err != nil {
+			return 0, 0, err
+		}
+		replicaQuota, err := strconv.ParseInt(strings.TrimSpace(values[2].String), 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		return dataQuota, replicaQuota, nil
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("no Quota in SHOW DATA of database %s", dbName)
+}
+
+// parseDataSize converts a data size such as 100GB, 1.5T or 1024.000 TB into bytes
+func parseDataSize(size string) (float64, error) {
+	m := dataSizeRegexp.FindStringSubmatch(strings.TrimSpace(size))
+	if m == nil {
+		return 0, fmt.Errorf("invalid data size: %q", size)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	exponent := strings.Index("KMGTP", strings.ToUpper(m[2])) + 1
+	if m[2] == "" {
+		exponent = 0
+	}
+	return value * math.Pow(1024, float64(exponent)), nil
+}
+
+// sameDataSize returns true if the sizes are equal within the rounding of SHOW DATA to three decimals
+func sameDataSize(a, b float64) bool {
+	return math.Abs(a-b) <= math.Max(a, b)*0.001
+}
+
+// finalizeMySQLDB drops MySQL database if it was created by the operator
 func (r *MySQLDBReconciler) finalizeMySQLDB(ctx context.Context, mysqlClient *sql.DB, mysqlDB *mysqlv1alpha1.MySQLDB) error {
-	_, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", mysqlDB.Spec.DBName))
-	return err
+	if mysqlDB.Status.Origin != mysqlv1alpha1.OriginCreated {
+		log.FromContext(ctx).Info("keep database not created by the operator", "database", mysqlDB.GetQualifiedName(), "origin", mysqlDB.Status.Origin)
+		return nil
+	}
+	_, err := mysqlClient.ExecContext(ctx, dropDatabaseStatement(mysqlDB))
+	if err != nil {
+		return err
+	}
+	r.Recorder.Eventf(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonDropped, "Dropped database %s", mysqlDB.GetQualifiedName())
+	return nil
+}
+
+// planFinalizeMySQLDB publishes the statement that finalizeMySQLDB would
+// execute to the status and an Event without executing it
+func (r *MySQLDBReconciler) planFinalizeMySQLDB(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) {
+	log := log.FromContext(ctx)
+	plan := []string{}
+	if mysqlDB.Status.Origin == mysqlv1alpha1.OriginCreated {
+		plan = append(plan, dropDatabaseStatement(mysqlDB))
+	}
+	log.Info("[Plan] Planned statements", "database", mysqlDB.GetQualifiedName(), "statements", len(plan))
+	r.Recorder.Event(mysqlDB, corev1.EventTypeNormal, mysqlDBEventReasonPlanned, formatPlan(plan))
+
+	mysqlDB.Status.Phase = mysqlDBPhasePlanned
+	mysqlDB.Status.Reason = mysqlDBReasonPlanned
+	mysqlDB.Status.Plan = plan
+	if serr := r.Status().Update(ctx, mysqlDB); serr != nil {
+		log.Error(serr, "Failed to update MySQLDB status", "Name", mysqlDB.GetQualifiedName())
+	}
+}
+
+func dropDatabaseStatement(mysqlDB *mysqlv1alpha1.MySQLDB) string {
+	return fmt.Sprintf("DROP DATABASE IF EXISTS %s", mysqlDB.GetQualifiedName())
+}
+
+// detachMySQLDB removes the finalizer
+func (r *MySQLDBReconciler) detachMySQLDB(ctx context.Context, mysqlDB *mysqlv1alpha1.MySQLDB) error {
+	if controllerutil.RemoveFinalizer(mysqlDB, mysqlDBFinalizer) {
+		return r.Update(ctx, mysqlDB)
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MySQLDBReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&mysqlv1alpha1.MySQLDB{}).
-		Complete(r)
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mysqlDBsForConfigMap))
+	if r.MigrationJob != nil {
+		// The Jobs are in the namespace of the operator, so they can't be owned by the MySQLDBs
+		builder = builder.Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.mysqlDBForMigrationJob))
+	}
+	return builder.Complete(r)
+}
+
+// mysqlDBsForConfigMap enqueues the MySQLDBs whose migrations are in the ConfigMap
+func (r *MySQLDBReconciler) mysqlDBsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	mysqlDBList := &mysqlv1alpha1.MySQLDBList{}
+	if err := r.List(ctx, mysqlDBList, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "[ConfigMap] Failed to list MySQLDBs", "configMap", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, mysqlDB := range mysqlDBList.Items {
+		if config := mysqlDB.Spec.SchemaMigrationFromConfigMap; config != nil && slices.Contains(config.Names, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mysqlDB.Namespace, Name: mysqlDB.Name}})
+		}
+	}
+	return requests
 }
@@ -0,0 +1,341 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	mysqlinternal "github.com/nakamasato/mysql-operator/internal/mysql"
+	webhookmysqlv1alpha1 "github.com/nakamasato/mysql-operator/internal/webhook/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	mysqlGrantFinalizer                   = "mysqlgrant.nakamasato.com/finalizer"
+	mysqlGrantPhaseReady                  = "Ready"
+	mysqlGrantPhaseNotReady               = "NotReady"
+	mysqlGrantReasonCompleted             = "Grants are successfully applied to the role"
+	mysqlGrantReasonMerged                = "Grants are merged into the MySQLUser"
+	mysqlGrantReasonMySQLUserNotFound     = "MySQLUser not found"
+	mysqlGrantReasonMySQLFetchFailed      = "Failed to fetch MySQL"
+	mysqlGrantReasonMySQLConnectionFailed = "Failed to connect to cluster"
+	mysqlGrantReasonFailedToGrant         = "Failed to grant"
+	mysqlGrantReasonInvalidGrants         = "Invalid grants"
+	mysqlGrantReasonNamespaceNotAllowed   = "Namespace not allowed by the MySQLUser"
+	mysqlGrantEventReasonConnectionFailed = "ConnectionFailed"
+	mysqlGrantEventReasonInvalidGrants    = "InvalidGrants"
+)
+
+// MySQLGrantReconciler reconciles a MySQLGrant object
+type MySQLGrantReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	MySQLClients mysqlinternal.MySQLClients
+	Recorder     record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlgrants,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlgrants/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlgrants/finalizers,verbs=update
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlusers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqls,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
+
+// Reconcile function is responsible for managing MySQLGrant.
+// Grants to a MySQLUser are merged into the grants of the MySQLUser by
+// MySQLUserReconciler. Grants to a role are applied here, and the grants
+// this MySQLGrant contributed are revoked when it is deleted.
+func (r *MySQLGrantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithName("MySQLGrantReconciler")
+
+	// Fetch MySQLGrant
+	mysqlGrant := &mysqlv1alpha1.MySQLGrant{}
+	err := r.Get(ctx, req.NamespacedName, mysqlGrant)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("[FetchMySQLGrant] Not found", "req.NamespacedName", req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "[FetchMySQLGrant] Failed")
+		return ctrl.Result{}, err
+	}
+
+	if mysqlGrant.Spec.UserRef != nil {
+		return r.reconcileUserGrant(ctx, mysqlGrant)
+	}
+
+	// Fetch MySQL
+	mysql := &mysqlv1alpha1.MySQL{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: mysqlGrant.Spec.ClusterName}, mysql); err != nil {
+		log.Error(err, "[FetchMySQL] Failed")
+		// Nothing can be revoked without the MySQL
+		if errors.IsNotFound(err) && !mysqlGrant.GetDeletionTimestamp().IsZero() {
+			return ctrl.Result{}, r.removeFinalizer(ctx, mysqlGrant)
+		}
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonMySQLFetchFailed, client.IgnoreNotFound(err))
+	}
+
+	// Get MySQL client
+	mysqlClient, err := r.MySQLClients.GetClient(mysql.GetKey())
+	if err != nil {
+		log.Error(err, "[MySQLClient] Failed to connect to cluster", "key", mysql.GetKey())
+		r.Recorder.Eventf(mysqlGrant, corev1.EventTypeWarning, mysqlGrantEventReasonConnectionFailed, "Failed to connect to cluster %s: %v", mysqlGrant.Spec.ClusterName, err)
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonMySQLConnectionFailed, err)
+	}
+	dialect, err := detectDialect(ctx, mysqlClient)
+	if err != nil {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonMySQLConnectionFailed, err)
+	}
+	to := grantee{object: mysqlGrant, identity: dialect.roleIdentity(mysqlGrant.Spec.Role)}
+
+	// Grants of the other MySQLGrants to the same role must be kept
+	others, err := r.otherRoleGrants(ctx, mysqlGrant)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Revoke the grants this MySQLGrant contributed if being deleted
+	if !mysqlGrant.GetDeletionTimestamp().IsZero() {
+		if controllerutil.ContainsFinalizer(mysqlGrant, mysqlGrantFinalizer) {
+			grantsToRevoke := subtractGrants(dialect, mysqlGrant.Status.AppliedGrants, others)
+			if _, err := applyGrantChanges(ctx, r.Recorder, mysqlClient, dialect, to, orderGrantChanges(grantsToRevoke, nil)); err != nil {
+				log.Error(err, "[Finalize] Failed to revoke grants", "role", mysqlGrant.Spec.Role)
+				return ctrl.Result{}, err
+			}
+			log.Info("[Finalize] Revoked grants", "role", mysqlGrant.Spec.Role, "grants", len(grantsToRevoke))
+		}
+		return ctrl.Result{}, r.removeFinalizer(ctx, mysqlGrant)
+	}
+
+	// Add finalizer for this CR
+	if controllerutil.AddFinalizer(mysqlGrant, mysqlGrantFinalizer) {
+		if err := r.Update(ctx, mysqlGrant); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.validateGrants(mysqlGrant, mysql.Spec.Flavor); err != nil {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonInvalidGrants, nil)
+	}
+
+	// Grant what the role doesn't have yet, and revoke what this MySQLGrant
+	// granted before and no longer has in the spec
+	existingGrants, err := fetchRoleGrants(ctx, mysqlClient, dialect, mysqlGrant.Spec.Role)
+	if err != nil {
+		log.Error(err, "[Grant] Failed to fetch grants", "role", mysqlGrant.Spec.Role)
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonFailedToGrant, err)
+	}
+	_, grantsToAdd := diffGrants(dialect, existingGrants, mysqlGrant.Spec.Grants)
+	obsoleteGrants, _ := diffGrants(dialect, mysqlGrant.Status.AppliedGrants, mysqlGrant.Spec.Grants)
+	grantsToRevoke := subtractGrants(dialect, obsoleteGrants, others)
+	if _, err := applyGrantChanges(ctx, r.Recorder, mysqlClient, dialect, to, orderGrantChanges(grantsToRevoke, grantsToAdd)); err != nil {
+		log.Error(err, "[Grant] Failed to update grants", "role", mysqlGrant.Spec.Role)
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonFailedToGrant, err)
+	}
+
+	// Only the grants issued by this MySQLGrant are revoked after it is deleted,
+	// not the ones the role had before
+	kept := subtractGrants(dialect, mysqlGrant.Status.AppliedGrants, obsoleteGrants)
+	mysqlGrant.Status.AppliedGrants = append(kept, grantsToAdd...)
+	mysqlGrant.Status.ObservedGeneration = mysqlGrant.Generation
+	return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseReady, mysqlGrantReasonCompleted, nil)
+}
+
+// reconcileUserGrant checks the referenced MySQLUser. The grants are applied
+// by MySQLUserReconciler, which also revokes them after this is deleted.
+func (r *MySQLGrantReconciler) reconcileUserGrant(ctx context.Context, mysqlGrant *mysqlv1alpha1.MySQLGrant) (ctrl.Result, error) {
+	if !mysqlGrant.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, r.removeFinalizer(ctx, mysqlGrant)
+	}
+	namespace, name := mysqlGrant.GetUserRef()
+	mysqlUser := &mysqlv1alpha1.MySQLUser{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, mysqlUser); err != nil {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonMySQLUserNotFound, client.IgnoreNotFound(err))
+	}
+	// The grants are ignored until the MySQLUser allows the namespace
+	if !mysqlUser.AllowsGrantsFrom(mysqlGrant.Namespace) {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonNamespaceNotAllowed, nil)
+	}
+	// The flavor is unknown until the MySQL of the MySQLUser exists
+	mysql := &mysqlv1alpha1.MySQL{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: mysqlUser.Spec.ClusterName}, mysql); client.IgnoreNotFound(err) != nil {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonMySQLFetchFailed, err)
+	}
+	if err := r.validateGrants(mysqlGrant, mysql.Spec.Flavor); err != nil {
+		return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseNotReady, mysqlGrantReasonInvalidGrants, nil)
+	}
+	mysqlGrant.Status.ObservedGeneration = mysqlGrant.Generation
+	return r.updateStatus(ctx, mysqlGrant, mysqlGrantPhaseReady, mysqlGrantReasonMerged, nil)
+}
+
+// validateGrants runs the validation of the MySQLUser webhook on the grants,
+// which the webhook never sees, and records a Warning Event if they are invalid
+func (r *MySQLGrantReconciler) validateGrants(mysqlGrant *mysqlv1alpha1.MySQLGrant, flavor mysqlv1alpha1.Flavor) error {
+	errs := webhookmysqlv1alpha1.ValidateGrants(field.NewPath("spec", "grants"), mysqlGrant.Spec.Grants, flavor)
+	if len(errs) == 0 {
+		return nil
+	}
+	r.Recorder.Eventf(mysqlGrant, corev1.EventTypeWarning, mysqlGrantEventReasonInvalidGrants, "Invalid grants: %v", errs.ToAggregate())
+	return errs.ToAggregate()
+}
+
+// fetchRoleGrants returns the grants of the role in the cluster
+func fetchRoleGrants(ctx context.Context, mysqlClient *sql.DB, dialect Dialect, role string) ([]mysqlv1alpha1.Grant, error) {
+	if dialect == DialectStarRocks {
+		grants, _, err := fetchExistingGrants(ctx, mysqlClient, dialect.roleIdentity(role))
+		return grants, err
+	}
+	// Doris shows the privileges of roles in SHOW ROLES, whose columns depend on the version
+	rows, err := mysqlClient.QueryContext(ctx, "SHOW ROLES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name sql.NullString
+		var row Grant
+		fields := map[string]*sql.NullString{
+			"Name":               &name,
+			"GlobalPrivs":        &row.GlobalPrivs,
+			"CatalogPrivs":       &row.CatalogPrivs,
+			"DatabasePrivs":      &row.DatabasePrivs,
+			"TablePrivs":         &row.TablePrivs,
+			"ColPrivs":           &row.ColPrivs,
+			"ResourcePrivs":      &row.ResourcePrivs,
+			"WorkloadGroupPrivs": &row.WorkloadGroupPrivs,
+		}
+		scanArgs := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if field, ok := fields[column]; ok {
+				scanArgs[i] = field
+			} else {
+				scanArgs[i] = new(sql.NullString)
+			}
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		if name.String == role {
+			return buildDorisGrants(row)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("role %s is not found", role)
+}
+
+// otherRoleGrants returns the grants of the other MySQLGrants to the same role
+func (r *MySQLGrantReconciler) otherRoleGrants(ctx context.Context, mysqlGrant *mysqlv1alpha1.MySQLGrant) ([]mysqlv1alpha1.Grant, error) {
+	mysqlGrantList := &mysqlv1alpha1.MySQLGrantList{}
+	if err := r.List(ctx, mysqlGrantList, client.InNamespace(mysqlGrant.Namespace)); err != nil {
+		return nil, err
+	}
+	grants := []mysqlv1alpha1.Grant{}
+	for _, other := range mysqlGrantList.Items {
+		if other.Name == mysqlGrant.Name || other.Spec.Role != mysqlGrant.Spec.Role ||
+			other.Spec.ClusterName != mysqlGrant.Spec.ClusterName || !other.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		grants = append(grants, other.Spec.Grants...)
+	}
+	return grants, nil
+}
+
+func (r *MySQLGrantReconciler) updateStatus(ctx context.Context, mysqlGrant *mysqlv1alpha1.MySQLGrant, phase, reason string, err error) (ctrl.Result, error) {
+	mysqlGrant.Status.Phase = phase
+	mysqlGrant.Status.Reason = reason
+	if serr := r.Status().Update(ctx, mysqlGrant); serr != nil {
+		log.FromContext(ctx).Error(serr, "Failed to update MySQLGrant status", "mysqlGrant", mysqlGrant.Name)
+		return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+	}
+	return ctrl.Result{}, err
+}
+
+// removeFinalizer removes mysqlGrantFinalizer so that the object can be deleted
+func (r *MySQLGrantReconciler) removeFinalizer(ctx context.Context, mysqlGrant *mysqlv1alpha1.MySQLGrant) error {
+	if controllerutil.RemoveFinalizer(mysqlGrant, mysqlGrantFinalizer) {
+		return r.Update(ctx, mysqlGrant)
+	}
+	return nil
+}
+
+// subtractGrants returns the privileges of grants that are not in others.
+// Privileges in others are kept regardless of the grant option, because
+// REVOKE removes the privilege with or without the grant option.
+func subtractGrants(dialect Dialect, grants, others []mysqlv1alpha1.Grant) []mysqlv1alpha1.Grant {
+	keep := []mysqlv1alpha1.Grant{}
+	for _, grant := range others {
+		flipped := grant
+		flipped.GrantOption = !grant.GrantOption
+		keep = append(keep, grant, flipped)
+	}
+	remaining, _ := calculateGrantDiff(dialect.normalizeGrants(grants), dialect.normalizeGrants(keep))
+	return mergeColumnGrants(remaining)
+}
+
+// roleIdentity returns the role in the syntax of TO and FROM clauses
+func (d Dialect) roleIdentity(role string) string {
+	if d == DialectDoris {
+		return fmt.Sprintf("ROLE '%s'", role)
+	}
+	return fmt.Sprintf("ROLE `%s`", role)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MySQLGrantReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&mysqlv1alpha1.MySQLGrant{}).
+		Watches(&mysqlv1alpha1.MySQLUser{}, handler.EnqueueRequestsFromMapFunc(r.mysqlGrantsForMySQLUser)).
+		Complete(r)
+}
+
+// mysqlGrantsForMySQLUser enqueues the MySQLGrants referencing the MySQLUser,
+// e.g. to update their status after the allowed namespaces are changed
+func (r *MySQLGrantReconciler) mysqlGrantsForMySQLUser(ctx context.Context, obj client.Object) []reconcile.Request {
+	mysqlGrantList := &mysqlv1alpha1.MySQLGrantList{}
+	if err := r.List(ctx, mysqlGrantList); err != nil {
+		log.FromContext(ctx).Error(err, "[MySQLUser] Failed to list MySQLGrants", "mysqlUser", obj.GetNamespace()+"/"+obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, mysqlGrant := range mysqlGrantList.Items {
+		if namespace, name := mysqlGrant.GetUserRef(); namespace == obj.GetNamespace() && name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mysqlGrant.Namespace, Name: mysqlGrant.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"database/sql/driver"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	mysqlinternal "github.com/nakamasato/mysql-operator/internal/mysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("MySQLGrant", func() {
+	table := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}
+
+	It("Should merge grants of MySQLGrants referencing the MySQLUser", func() {
+		mysqlUser := &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec:       mysqlv1alpha1.MySQLUserSpec{Grants: []mysqlv1alpha1.Grant{{Privileges: []string{"INSERT"}, Object: table}}},
+		}
+		readOnly := &mysqlv1alpha1.MySQLGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "read-db1", Namespace: "team-b", Generation: 2},
+			Spec: mysqlv1alpha1.MySQLGrantSpec{
+				UserRef: &mysqlv1alpha1.MySQLUserReference{Name: "app", Namespace: "team-a"},
+				Grants:  []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: table}},
+			},
+		}
+		otherUser := &mysqlv1alpha1.MySQLGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-b"},
+			Spec: mysqlv1alpha1.MySQLGrantSpec{
+				UserRef: &mysqlv1alpha1.MySQLUserReference{Name: "app"},
+				Grants:  []mysqlv1alpha1.Grant{{Privileges: []string{"DELETE"}, Object: table}},
+			},
+		}
+		reconciler := &MySQLUserReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(readOnly, otherUser).Build()}
+
+		// A MySQLGrant in another namespace is ignored without the consent of the user
+		grants, sources, err := reconciler.desiredGrants(context.TODO(), mysqlUser, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sources.mysqlGrants).To(BeEmpty())
+		Expect(grants).To(HaveLen(1))
+
+		mysqlUser.Spec.AllowedGrantNamespaces = []string{"team-b"}
+		grants, sources, err = reconciler.desiredGrants(context.TODO(), mysqlUser, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sources.mysqlGrants).To(Equal([]string{"team-b/read-db1/2"}))
+		Expect(grants).To(HaveLen(2))
+
+		// Privileges on the same object are merged instead of overriding each other
+		grantsToRevoke, grantsToAdd := diffGrants(DialectStarRocks, []mysqlv1alpha1.Grant{{Privileges: []string{"INSERT", "SELECT"}, Object: table}}, grants)
+		Expect(grantsToRevoke).To(BeEmpty())
+		Expect(grantsToAdd).To(BeEmpty())
+
+		Expect(mysqlUserForMySQLGrant(context.TODO(), otherUser)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "team-b", Name: "app"}},
+		}))
+	})
+
+	It("Should report a MySQLGrant from a namespace the MySQLUser doesn't allow", func() {
+		mysqlUser := &mysqlv1alpha1.MySQLUser{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}}
+		mysqlGrant := &mysqlv1alpha1.MySQLGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "read-db1", Namespace: "team-b"},
+			Spec: mysqlv1alpha1.MySQLGrantSpec{
+				UserRef: &mysqlv1alpha1.MySQLUserReference{Name: "app", Namespace: "team-a"},
+				Grants:  []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: table}},
+			},
+		}
+		reconciler := &MySQLGrantReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysqlUser, mysqlGrant).WithStatusSubresource(mysqlGrant).Build(),
+			Recorder: record.NewFakeRecorder(10),
+		}
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-b", Name: "read-db1"}}
+
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(context.TODO(), req.NamespacedName, mysqlGrant)).To(Succeed())
+		Expect(mysqlGrant.Status.Phase).To(Equal(mysqlGrantPhaseNotReady))
+		Expect(mysqlGrant.Status.Reason).To(Equal(mysqlGrantReasonNamespaceNotAllowed))
+
+		// Allowing the namespace reconciles the MySQLGrant
+		Expect(reconciler.mysqlGrantsForMySQLUser(context.TODO(), mysqlUser)).To(Equal([]reconcile.Request{req}))
+		mysqlUser.Spec.AllowedGrantNamespaces = []string{"*"}
+		Expect(reconciler.Update(context.TODO(), mysqlUser)).To(Succeed())
+		_, err = reconciler.Reconcile(context.TODO(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(context.TODO(), req.NamespacedName, mysqlGrant)).To(Succeed())
+		Expect(mysqlGrant.Status.Phase).To(Equal(mysqlGrantPhaseReady))
+		Expect(mysqlGrant.Status.Reason).To(Equal(mysqlGrantReasonMerged))
+	})
+
+	It("Should reject invalid grants of MySQLGrants", func() {
+		mysqlUser := &mysqlv1alpha1.MySQLUser{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}}
+		invalid := &mysqlv1alpha1.MySQLGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "team-a"},
+			Spec: mysqlv1alpha1.MySQLGrantSpec{
+				UserRef: &mysqlv1alpha1.MySQLUserReference{Name: "app"},
+				Grants:  []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT", "NODE"}, Object: table}},
+			},
+		}
+		reconciler := &MySQLUserReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(invalid).Build()}
+
+		_, _, err := reconciler.desiredGrants(context.TODO(), mysqlUser, mysqlv1alpha1.FlavorStarRocks)
+		Expect(err).To(MatchError(ContainSubstring("grants of MySQLGrant team-a/invalid are invalid: spec.grants[0].privileges[1]")))
+
+		// The MySQLGrant reports the invalid grants
+		mysql := &mysqlv1alpha1.MySQL{
+			ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "team-a"},
+			Spec:       mysqlv1alpha1.MySQLSpec{Flavor: mysqlv1alpha1.FlavorStarRocks},
+		}
+		mysqlUser.Spec.ClusterName = "starrocks"
+		recorder := record.NewFakeRecorder(10)
+		grantReconciler := &MySQLGrantReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, mysqlUser, invalid).WithStatusSubresource(invalid).Build(),
+			Recorder: recorder,
+		}
+		_, err = grantReconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "invalid"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Warning InvalidGrants Invalid grants: spec.grants[0].privileges[1]")))
+		Expect(grantReconciler.Get(context.TODO(), client.ObjectKeyFromObject(invalid), invalid)).To(Succeed())
+		Expect(invalid.Status.Phase).To(Equal(mysqlGrantPhaseNotReady))
+		Expect(invalid.Status.Reason).To(Equal(mysqlGrantReasonInvalidGrants))
+	})
+
+	It("Should grant to a role only what it doesn't have, and revoke only that after deletion", func() {
+		mysql := &mysqlv1alpha1.MySQL{ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "team-a"}}
+		mysqlGrant := &mysqlv1alpha1.MySQLGrant{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyst", Namespace: "team-a"},
+			Spec: mysqlv1alpha1.MySQLGrantSpec{
+				Role:        "analyst",
+				ClusterName: "starrocks",
+				Grants:      []mysqlv1alpha1.Grant{{Privileges: []string{"INSERT", "SELECT"}, Object: table}},
+			},
+		}
+		db, fakeDB := newFakeDB()
+		defer db.Close()
+		// The role had SELECT before the MySQLGrant
+		fakeDB.rows = func(query string) ([]string, [][]driver.Value) {
+			columns := []string{"UserIdentity", "Catalog", "Grants"}
+			if query == "SHOW GRANTS FOR ROLE `analyst`;" {
+				return columns, [][]driver.Value{{"analyst", "default_catalog", "GRANT SELECT ON TABLE db1.tbl1 TO ROLE 'analyst'"}}
+			}
+			return columns, nil
+		}
+		reconciler := &MySQLGrantReconciler{
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, mysqlGrant).WithStatusSubresource(mysqlGrant).Build(),
+			MySQLClients: mysqlinternal.MySQLClients{mysql.GetKey(): db},
+			Recorder:     record.NewFakeRecorder(10),
+		}
+		req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlGrant)}
+
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeDB.Statements()).To(Equal([]string{"GRANT INSERT ON TABLE db1.tbl1 TO ROLE `analyst`;"}))
+		Expect(reconciler.Get(context.TODO(), req.NamespacedName, mysqlGrant)).To(Succeed())
+		Expect(mysqlGrant.Status.Phase).To(Equal(mysqlGrantPhaseReady))
+		Expect(mysqlGrant.Status.AppliedGrants).To(Equal([]mysqlv1alpha1.Grant{{Privileges: []string{"INSERT"}, Object: table}}))
+
+		// SELECT the role had before is kept
+		Expect(reconciler.Delete(context.TODO(), mysqlGrant)).To(Succeed())
+		_, err = reconciler.Reconcile(context.TODO(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeDB.Statements()).To(Equal([]string{
+			"GRANT INSERT ON TABLE db1.tbl1 TO ROLE `analyst`;",
+			"REVOKE INSERT ON TABLE db1.tbl1 FROM ROLE `analyst`;",
+		}))
+	})
+
+	It("Should read the grants of a Doris role from SHOW ROLES", func() {
+		db, fakeDB := newFakeDB()
+		defer db.Close()
+		fakeDB.rows = func(query string) ([]string, [][]driver.Value) {
+			return []string{"Name", "Comment", "Users", "GlobalPrivs", "CatalogPrivs", "DatabasePrivs", "TablePrivs", "ResourcePrivs", "WorkloadGroupPrivs"}, [][]driver.Value{
+				{"admin", "", "", "Admin_priv", nil, nil, nil, nil, nil},
+				{"analyst", "", "'app'@'%'", nil, nil, "internal.db1: Select_priv", nil, nil, nil},
+			}
+		}
+		grants, err := fetchRoleGrants(context.TODO(), db, DialectDoris, "analyst")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: "internal", Name: "db1"}},
+		}))
+
+		_, err = fetchRoleGrants(context.TODO(), db, DialectDoris, "reader")
+		Expect(err).To(MatchError("role reader is not found"))
+	})
+
+	It("Should revoke only the grants that no other MySQLGrant contributes", func() {
+		grants := []mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT", "INSERT"}, Object: table},
+			{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}},
+		}
+		others := []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: table, GrantOption: true}}
+		Expect(subtractGrants(DialectStarRocks, grants, others)).To(ConsistOf(
+			mysqlv1alpha1.Grant{Privileges: []string{"INSERT"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}},
+		))
+	})
+
+	It("Should grant to roles in the syntax of each dialect", func() {
+		grant := mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table}
+		Expect(DialectStarRocks.grantStatement(DialectStarRocks.roleIdentity("analyst"), grant)).To(Equal("GRANT SELECT ON TABLE db1.tbl1 TO ROLE `analyst`;"))
+		Expect(DialectDoris.revokeStatement(DialectDoris.roleIdentity("analyst"), grant)).To(Equal("REVOKE SELECT ON internal.db1.tbl1 FROM ROLE 'analyst';"))
+	})
+})
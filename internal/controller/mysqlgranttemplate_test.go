@@ -0,0 +1,109 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("MySQLGrantTemplate", func() {
+	readOnly := &mysqlv1alpha1.MySQLGrantTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "readonly", Generation: 3},
+		Spec: mysqlv1alpha1.MySQLGrantTemplateSpec{
+			Grants: []mysqlv1alpha1.Grant{
+				{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "{{database}}", Name: "*"}},
+				{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "{{ database }}_spark"}},
+			},
+		},
+	}
+
+	It("Should expand placeholders with the parameters", func() {
+		grants, err := readOnly.Expand(map[string]string{"database": "db1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "*"}},
+			{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "db1_spark"}},
+		}))
+		// The template itself is not changed
+		Expect(readOnly.Spec.Grants[0].Object.Database).To(Equal("{{database}}"))
+
+		_, err = readOnly.Expand(nil)
+		Expect(err).To(MatchError(`parameters "database" of MySQLGrantTemplate readonly are not given`))
+
+		// Every missing parameter is reported once
+		readWrite := &mysqlv1alpha1.MySQLGrantTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "readwrite"},
+			Spec: mysqlv1alpha1.MySQLGrantTemplateSpec{
+				Grants: []mysqlv1alpha1.Grant{
+					{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "{{database}}", Name: "{{table}}"}},
+					{Privileges: []string{"INSERT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "{{database}}", Name: "{{table}}"}},
+				},
+			},
+		}
+		_, err = readWrite.Expand(map[string]string{"schema": "db1"})
+		Expect(err).To(MatchError(`parameters "database", "table" of MySQLGrantTemplate readwrite are not given`))
+	})
+
+	It("Should merge the expanded templates into the grants of the MySQLUser", func() {
+		mysqlUser := &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: mysqlv1alpha1.MySQLUserSpec{
+				GrantTemplates: []mysqlv1alpha1.GrantTemplateReference{{Name: "readonly", Parameters: map[string]string{"database": "db1"}}},
+			},
+		}
+		otherUser := &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-b"},
+		}
+		reconciler := &MySQLUserReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(readOnly, mysqlUser, otherUser).Build()}
+
+		grants, sources, err := reconciler.desiredGrants(context.TODO(), mysqlUser, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sources.grantTemplates).To(Equal([]string{"readonly/3"}))
+		Expect(grants).To(HaveLen(2))
+		Expect(grants[0].Object.Database).To(Equal("db1"))
+
+		// A change of the template is not a drift
+		mysqlUser.Status.ObservedMySQLGrants = []string{}
+		mysqlUser.Status.ObservedGrantTemplates = []string{"readonly/2"}
+		Expect(sources.observedBy(mysqlUser)).To(BeFalse())
+		mysqlUser.Status.ObservedGrantTemplates = []string{"readonly/3"}
+		Expect(sources.observedBy(mysqlUser)).To(BeTrue())
+
+		// Only the users referencing the template are reconciled
+		Expect(reconciler.mysqlUsersForGrantTemplate(context.TODO(), readOnly)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "app"}},
+		}))
+
+		// Expanded grants are validated
+		mysqlUser.Spec.GrantTemplates[0].Parameters["database"] = "db1; DROP USER root"
+		_, _, err = reconciler.desiredGrants(context.TODO(), mysqlUser, "")
+		Expect(err).To(MatchError(ContainSubstring("expanded grants of MySQLGrantTemplate readonly are invalid: [spec.grants[0].object.database")))
+
+		// A missing template fails
+		mysqlUser.Spec.GrantTemplates[0].Name = "readwrite"
+		_, _, err = reconciler.desiredGrants(context.TODO(), mysqlUser, "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -19,19 +19,27 @@ package controllers
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -40,6 +48,7 @@ import (
 	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
 	"github.com/nakamasato/mysql-operator/internal/metrics"
 	mysqlinternal "github.com/nakamasato/mysql-operator/internal/mysql"
+	webhookmysqlv1alpha1 "github.com/nakamasato/mysql-operator/internal/webhook/v1alpha1"
 )
 
 const (
@@ -51,8 +60,33 @@ const (
 	mysqlUserReasonMySQLFailedToGetSecret      = "Failed to get Secret"
 	mysqlUserReasonMYSQLFailedToGrant          = "Failed to grant"
 	mysqlUserReasonMySQLFetchFailed            = "Failed to fetch cluster"
+	mysqlUserReasonAlreadyExists               = "User already exists"
+	mysqlUserReasonAdoptedReadOnly             = "User is adopted as read-only"
+	mysqlUserReasonPlanned                     = "Statements are planned but not executed"
+	mysqlUserReasonGrantWithoutObject          = "Grant has no object"
 	mysqlUserPhaseReady                        = "Ready"
 	mysqlUserPhaseNotReady                     = "NotReady"
+	mysqlUserPhasePlanned                      = "Planned"
+	mysqlUserConditionDrifted                  = "Drifted"
+	mysqlUserConditionReasonInSync             = "InSync"
+	mysqlUserConditionReasonDriftDetected      = "DriftDetected"
+	mysqlUserConditionReasonDriftCorrected     = "DriftCorrected"
+	mysqlUserEventReasonGrantDrift             = "GrantDrift"
+	mysqlUserEventReasonConnectionFailed       = "ConnectionFailed"
+	mysqlUserEventReasonCreated                = "CreatedUser"
+	mysqlUserEventReasonFailedToCreate         = "FailedToCreateUser"
+	mysqlUserEventReasonAdopted                = "AdoptedUser"
+	mysqlUserEventReasonPasswordUpdated        = "UpdatedPassword"
+	mysqlUserEventReasonFailedToUpdatePassword = "FailedToUpdatePassword"
+	mysqlUserEventReasonGranted                = "Granted"
+	mysqlUserEventReasonRevoked                = "Revoked"
+	mysqlUserEventReasonFailedToGrant          = "FailedToGrant"
+	mysqlUserEventReasonDropped                = "DroppedUser"
+	mysqlUserEventReasonPlanned                = "Planned"
+	mysqlUserEventReasonRolledBack             = "RolledBackGrants"
+	mysqlUserEventReasonFailedToRollBack       = "FailedToRollBackGrants"
+	mysqlUserEventReasonGrantWithoutObject     = "GrantWithoutObject"
+	redactedPassword                           = "****"
 )
 
 // MySQLUserReconciler reconciles a MySQLUser object
@@ -60,12 +94,19 @@ type MySQLUserReconciler struct {
 	client.Client
 	Scheme       *runtime.Scheme
 	MySQLClients mysqlinternal.MySQLClients
+	Recorder     record.EventRecorder
+	// PlanOnly plans statements for all users without executing them
+	PlanOnly bool
+	// GrantCache caches the grants of all users per cluster if set
+	GrantCache *GrantCache
 }
 
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlusers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlusers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlusers/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlgrants,verbs=get;list;watch
+//+kubebuilder:rbac:groups=mysql.nakamasato.com,resources=mysqlgranttemplates,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 // Reconcile function is responsible for managing MySQLUser.
@@ -90,7 +131,12 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	clusterName := mysqlUser.Spec.ClusterName
 	userIdentity := mysqlUser.GetUserIdentity()
 	secretRef := mysqlUser.Spec.SecretRef
-	grants := mysqlUser.Spec.Grants
+
+	// Orphan the user without looking up MySQL, which might be already gone
+	if !mysqlUser.GetDeletionTimestamp().IsZero() && mysqlUser.Spec.DeletionPolicy == mysqlv1alpha1.DeletionPolicyOrphan {
+		log.Info("[Finalize] Orphan MySQL user", "userIdentity", userIdentity)
+		return ctrl.Result{}, r.removeFinalizer(ctx, mysqlUser)
+	}
 
 	// Fetch MySQL
 	mysql := &mysqlv1alpha1.MySQL{}
@@ -107,6 +153,41 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 	log.Info("[FetchMySQL] Found")
 
+	// Users created by an operator that only set userCreated are still dropped after deletion
+	if mysqlUser.Status.UserCreated && mysqlUser.Status.Origin == "" {
+		mysqlUser.Status.Origin = mysqlv1alpha1.OriginCreated
+		if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+			log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+			return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+		}
+	}
+
+	// Keep the user in MySQL unless deletionPolicy is Delete
+	planOnly := r.PlanOnly || mysqlUser.Spec.PlanOnly
+	if !mysqlUser.GetDeletionTimestamp().IsZero() {
+		if deletionPolicy := mysqlUser.GetDeletionPolicy(mysql); deletionPolicy != mysqlv1alpha1.DeletionPolicyDelete || planOnly {
+			log.Info("[Finalize] Keep MySQL user", "userIdentity", userIdentity, "deletionPolicy", deletionPolicy, "planOnly", planOnly)
+			if deletionPolicy == mysqlv1alpha1.DeletionPolicyDelete {
+				r.planFinalizeMySQLUser(ctx, mysqlUser)
+			}
+			return ctrl.Result{}, r.removeFinalizer(ctx, mysqlUser)
+		}
+	}
+
+	// Grants stored with the removed target field have no object, and would
+	// render invalid statements and revoke the grants in the cluster
+	if indexes := grantsWithoutObject(mysqlUser.Spec.Grants); len(indexes) > 0 && mysqlUser.GetDeletionTimestamp().IsZero() {
+		log.Info("[Grants] Grants without object", "indexes", indexes)
+		r.Recorder.Eventf(mysqlUser, v1.EventTypeWarning, mysqlUserEventReasonGrantWithoutObject, "Grants %v of user %s have no object: replace target with object in the grants", indexes, userIdentity)
+		mysqlUser.Status.Phase = mysqlUserPhaseNotReady
+		mysqlUser.Status.Reason = mysqlUserReasonGrantWithoutObject
+		if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+			log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+			return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// SetOwnerReference if not exists
 	if !r.ifOwnerReferencesContains(mysqlUser.OwnerReferences, mysql) {
 		err := controllerutil.SetControllerReference(mysql, mysqlUser, r.Scheme)
@@ -125,6 +206,7 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		mysqlUser.Status.Phase = mysqlUserPhaseNotReady
 		mysqlUser.Status.Reason = mysqlUserReasonMySQLConnectionFailed
 		log.Error(err, "[MySQLClient] Failed to connect to cluster", "key", mysql.GetKey(), "clusterName", clusterName)
+		r.Recorder.Eventf(mysqlUser, v1.EventTypeWarning, mysqlUserEventReasonConnectionFailed, "Failed to connect to cluster %s: %v", clusterName, err)
 		if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
 			log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
 			return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
@@ -141,7 +223,7 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			// Run finalization logic for mysqlUserFinalizer. If the
 			// finalization logic fails, don't remove the finalizer so
 			// that we can retry during the next reconciliation.
-			if err := r.finalizeMySQLUser(ctx, mysqlClient, mysqlUser); err != nil {
+			if err := r.finalizeMySQLUser(ctx, mysql.GetKey(), mysqlClient, mysqlUser); err != nil {
 				log.Error(err, "Failed to complete finalizeMySQLUser")
 				return ctrl.Result{}, err
 			}
@@ -200,14 +282,30 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	log.Info("[password] Get password from Secret", "secretRef", secretRef)
 	password := string(secret.Data[secretRef.Key])
 
+	// Only plan the statements without executing them
+	if planOnly {
+		return r.planMySQLUser(ctx, mysqlClient, mysqlUser, mysql)
+	}
+
 	// Check if MySQL user exists
 	_, err = mysqlClient.ExecContext(ctx, fmt.Sprintf("SHOW GRANTS FOR %s", userIdentity))
 	if err != nil {
+		// Record the origin before creating the user, so that the next reconcile
+		// doesn't adopt the user if the status can't be updated after creating it
+		if mysqlUser.Status.Origin != mysqlv1alpha1.OriginCreated {
+			mysqlUser.Status.Origin = mysqlv1alpha1.OriginCreated
+			if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+				log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+			}
+		}
 		// Create User if not exists with the password set above.
 		_, err = mysqlClient.ExecContext(ctx,
-			fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY '%s'", userIdentity, password))
+			createUserStatement(userIdentity, password))
 		if err != nil {
 			log.Error(err, "[MySQL] Failed to create User", "clusterName", clusterName, "userIdentity", userIdentity)
+			// The error might contain the statement with the password
+			r.Recorder.Eventf(mysqlUser, v1.EventTypeWarning, mysqlUserEventReasonFailedToCreate, "Failed to create user %s", userIdentity)
 			mysqlUser.Status.Phase = mysqlUserPhaseNotReady
 			mysqlUser.Status.Reason = mysqlUserReasonMySQLFailedToCreateUser
 			if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
@@ -217,15 +315,46 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{}, err //requeue
 		}
 		log.Info("[MySQL] Created User", "clusterName", clusterName, "userIdentity", userIdentity)
+		r.invalidateGrants(mysql.GetKey(), userIdentity)
+		r.Recorder.Eventf(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonCreated, "Created user %s", userIdentity)
 		mysqlUser.Status.UserCreated = true
 		metrics.MysqlUserCreatedTotal.Increment()
 	} else {
+		if mysqlUser.Status.Origin == "" {
+			// The user exists but was neither created nor adopted by the operator
+			if mysqlUser.Spec.AdoptionPolicy == mysqlv1alpha1.AdoptionPolicyFailIfExists {
+				log.Info("[MySQL] User already exists", "clusterName", clusterName, "userIdentity", userIdentity)
+				mysqlUser.Status.Phase = mysqlUserPhaseNotReady
+				mysqlUser.Status.Reason = mysqlUserReasonAlreadyExists
+				if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+					log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+					return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+				}
+				return ctrl.Result{}, nil
+			}
+			log.Info("[MySQL] Adopted User", "clusterName", clusterName, "userIdentity", userIdentity, "adoptionPolicy", mysqlUser.Spec.AdoptionPolicy)
+			mysqlUser.Status.Origin = mysqlv1alpha1.OriginAdopted
+			r.Recorder.Eventf(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonAdopted, "Adopted existing user %s", userIdentity)
+		}
 		mysqlUser.Status.UserCreated = true
+
+		// Neither password nor grants are changed for a read-only user
+		if mysqlUser.IsReadOnly() {
+			mysqlUser.Status.Phase = mysqlUserPhaseReady
+			mysqlUser.Status.Reason = mysqlUserReasonAdoptedReadOnly
+			if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+				log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+			}
+			return ctrl.Result{}, nil
+		}
+
 		// Update password of User if already exists with the password set above.
 		_, err = mysqlClient.ExecContext(ctx,
-			fmt.Sprintf("ALTER USER %s IDENTIFIED BY '%s'", userIdentity, password))
+			alterUserStatement(userIdentity, password))
 		if err != nil {
 			log.Error(err, "[MySQL] Failed to update password of User", "clusterName", clusterName, "userIdentity", userIdentity)
+			// The error might contain the statement with the password
+			r.Recorder.Eventf(mysqlUser, v1.EventTypeWarning, mysqlUserEventReasonFailedToUpdatePassword, "Failed to update password of user %s", userIdentity)
 			mysqlUser.Status.Phase = mysqlUserPhaseNotReady
 			mysqlUser.Status.Reason = mysqlUserReasonMySQLFailedToUpdatePassword
 			if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
@@ -235,10 +364,14 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{}, err //requeue
 		}
 		log.Info("[MySQL] Updated password of User", "clusterName", clusterName, "userIdentity", userIdentity)
+		r.Recorder.Eventf(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonPasswordUpdated, "Updated password of user %s from Secret %s", userIdentity, secretRef.Name)
 	}
 
 	// Update Grants
-	err = r.updateGrants(ctx, mysqlClient, userIdentity, grants)
+	grants, sources, err := r.desiredGrants(ctx, mysqlUser, mysql.Spec.Flavor)
+	if err == nil {
+		err = r.updateGrants(ctx, mysql.GetKey(), mysqlClient, mysqlUser, grants, sources, mysqlUser.GetDriftPolicy(mysql))
+	}
 	if err != nil {
 		log.Error(err, "[MySQL] Failed to update Grants", "clusterName", clusterName, "userIdentity", userIdentity)
 		mysqlUser.Status.Phase = mysqlUserPhaseNotReady
@@ -252,33 +385,256 @@ func (r *MySQLUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Update phase and reason of MySQLUser status to Ready and Completed
 	mysqlUser.Status.Phase = mysqlUserPhaseReady
 	mysqlUser.Status.Reason = mysqlUserReasonCompleted
+	mysqlUser.Status.ObservedGeneration = mysqlUser.Generation
+	mysqlUser.Status.ObservedMySQLGrants = sources.mysqlGrants
+	mysqlUser.Status.ObservedGrantTemplates = sources.grantTemplates
+	mysqlUser.Status.Plan = nil
 	if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
 		log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
 	}
 
-	return ctrl.Result{}, nil
+	// Check grants for drift periodically
+	return ctrl.Result{RequeueAfter: mysqlUser.GetResyncInterval(mysql)}, nil
+}
+
+// grantsWithoutObject returns the indexes of the grants without object,
+// e.g. grants with target stored before object replaced it
+func grantsWithoutObject(grants []mysqlv1alpha1.Grant) []int {
+	var indexes []int
+	for i, grant := range grants {
+		if grant.Object.Kind == "" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// planMySQLUser publishes the statements to create or alter the user and to
+// update its grants to the status and an Event without executing them.
+func (r *MySQLUserReconciler) planMySQLUser(ctx context.Context, mysqlClient *sql.DB, mysqlUser *mysqlv1alpha1.MySQLUser, mysql *mysqlv1alpha1.MySQL) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	userIdentity := mysqlUser.GetUserIdentity()
+
+	plan, err := r.planStatements(ctx, mysqlClient, mysqlUser, mysql.Spec.Flavor)
+	if err != nil {
+		log.Error(err, "[Plan] Failed to plan statements", "userIdentity", userIdentity)
+		mysqlUser.Status.Phase = mysqlUserPhaseNotReady
+		mysqlUser.Status.Reason = mysqlUserReasonMYSQLFailedToGrant
+		if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+			log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+			return ctrl.Result{RequeueAfter: time.Second}, nil // requeue after 1 second
+		}
+		return ctrl.Result{}, err
+	}
+	log.Info("[Plan] Planned statements", "userIdentity", userIdentity, "statements", len(plan))
+	r.Recorder.Event(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonPlanned, formatPlan(plan))
+
+	mysqlUser.Status.Phase = mysqlUserPhasePlanned
+	mysqlUser.Status.Reason = mysqlUserReasonPlanned
+	mysqlUser.Status.Plan = plan
+	if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+		log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+	}
+	return ctrl.Result{RequeueAfter: mysqlUser.GetResyncInterval(mysql)}, nil
+}
+
+// planStatements returns the ordered statements that a reconciliation would execute
+func (r *MySQLUserReconciler) planStatements(ctx context.Context, mysqlClient *sql.DB, mysqlUser *mysqlv1alpha1.MySQLUser, flavor mysqlv1alpha1.Flavor) ([]string, error) {
+	userIdentity := mysqlUser.GetUserIdentity()
+	plan := []string{}
+
+	var existingGrants []mysqlv1alpha1.Grant
+	var dialect Dialect
+	if _, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("SHOW GRANTS FOR %s", userIdentity)); err != nil {
+		plan = append(plan, createUserStatement(userIdentity, redactedPassword))
+		if dialect, err = detectDialect(ctx, mysqlClient); err != nil {
+			return nil, err
+		}
+	} else {
+		// The operator doesn't touch an existing user that is not adopted or read-only
+		notAdopted := mysqlUser.Status.Origin == "" &&
+			(mysqlUser.Spec.AdoptionPolicy == mysqlv1alpha1.AdoptionPolicyFailIfExists || mysqlUser.Spec.AdoptionPolicy == mysqlv1alpha1.AdoptionPolicyAdoptReadOnly)
+		if notAdopted || mysqlUser.IsReadOnly() {
+			return plan, nil
+		}
+		plan = append(plan, alterUserStatement(userIdentity, redactedPassword))
+		if existingGrants, dialect, err = fetchExistingGrants(ctx, mysqlClient, userIdentity); err != nil {
+			return nil, err
+		}
+	}
+
+	grants, _, err := r.desiredGrants(ctx, mysqlUser, flavor)
+	if err != nil {
+		return nil, err
+	}
+	grantsToRevoke, grantsToAdd := diffGrants(dialect, existingGrants, grants)
+	return append(plan, grantStatements(dialect, userIdentity, orderGrantChanges(grantsToRevoke, grantsToAdd))...), nil
+}
+
+// formatPlan returns a message of the planned statements for an Event
+func formatPlan(plan []string) string {
+	if len(plan) == 0 {
+		return "No statements planned"
+	}
+	return fmt.Sprintf("Planned %d statements: %s", len(plan), strings.Join(plan, "\n"))
+}
+
+func createUserStatement(userIdentity, password string) string {
+	return fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY '%s'", userIdentity, password)
+}
+
+func alterUserStatement(userIdentity, password string) string {
+	return fmt.Sprintf("ALTER USER %s IDENTIFIED BY '%s'", userIdentity, password)
+}
+
+// grantSources are the objects besides the spec that the grants of a MySQLUser come from
+type grantSources struct {
+	// MySQLGrants as namespace/name/generation
+	mysqlGrants []string
+	// MySQLGrantTemplates as name/generation
+	grantTemplates []string
+}
+
+// observedBy returns true if the grants of the sources were last applied to the user
+func (s grantSources) observedBy(mysqlUser *mysqlv1alpha1.MySQLUser) bool {
+	return slices.Equal(mysqlUser.Status.ObservedMySQLGrants, s.mysqlGrants) &&
+		slices.Equal(mysqlUser.Status.ObservedGrantTemplates, s.grantTemplates)
+}
+
+// desiredGrants returns the grants in the spec merged with the expanded
+// MySQLGrantTemplates and the grants of MySQLGrants referencing the user
+// from the namespaces the user allows.
+// The grants of the spec are validated by the webhook, the others are
+// validated here for the flavor of the cluster.
+func (r *MySQLUserReconciler) desiredGrants(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser, flavor mysqlv1alpha1.Flavor) ([]mysqlv1alpha1.Grant, grantSources, error) {
+	grants := append([]mysqlv1alpha1.Grant{}, mysqlUser.Spec.Grants...)
+	sources := grantSources{mysqlGrants: []string{}, grantTemplates: []string{}}
+
+	for _, ref := range mysqlUser.Spec.GrantTemplates {
+		template := &mysqlv1alpha1.MySQLGrantTemplate{}
+		if err := r.Get(ctx, client.ObjectKey{Name: ref.Name}, template); err != nil {
+			return nil, sources, err
+		}
+		expanded, err := template.Expand(ref.Parameters)
+		if err != nil {
+			return nil, sources, err
+		}
+		if errs := webhookmysqlv1alpha1.ValidateGrants(field.NewPath("spec", "grants"), expanded, flavor); len(errs) > 0 {
+			return nil, sources, fmt.Errorf("expanded grants of MySQLGrantTemplate %s are invalid: %w", template.Name, errs.ToAggregate())
+		}
+		grants = append(grants, expanded...)
+		sources.grantTemplates = append(sources.grantTemplates, fmt.Sprintf("%s/%d", template.Name, template.Generation))
+	}
+
+	mysqlGrantList := &mysqlv1alpha1.MySQLGrantList{}
+	if err := r.List(ctx, mysqlGrantList); err != nil {
+		return nil, sources, err
+	}
+	for _, mysqlGrant := range mysqlGrantList.Items {
+		namespace, name := mysqlGrant.GetUserRef()
+		if namespace != mysqlUser.Namespace || name != mysqlUser.Name || !mysqlGrant.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		// A MySQLGrant in another namespace needs the consent of the user
+		if !mysqlUser.AllowsGrantsFrom(mysqlGrant.Namespace) {
+			log.FromContext(ctx).Info("[Grant] Ignored MySQLGrant from a namespace not allowed by the MySQLUser", "mysqlGrant", mysqlGrant.Namespace+"/"+mysqlGrant.Name)
+			continue
+		}
+		if errs := webhookmysqlv1alpha1.ValidateGrants(field.NewPath("spec", "grants"), mysqlGrant.Spec.Grants, flavor); len(errs) > 0 {
+			return nil, sources, fmt.Errorf("grants of MySQLGrant %s/%s are invalid: %w", mysqlGrant.Namespace, mysqlGrant.Name, errs.ToAggregate())
+		}
+		grants = append(grants, mysqlGrant.Spec.Grants...)
+		sources.mysqlGrants = append(sources.mysqlGrants, fmt.Sprintf("%s/%s/%d", mysqlGrant.Namespace, mysqlGrant.Name, mysqlGrant.Generation))
+	}
+	sort.Strings(sources.mysqlGrants)
+	sort.Strings(sources.grantTemplates)
+	return grants, sources, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MySQLUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&mysqlv1alpha1.MySQLUser{}).
+		Watches(&mysqlv1alpha1.MySQLGrant{}, handler.EnqueueRequestsFromMapFunc(mysqlUserForMySQLGrant)).
+		Watches(&mysqlv1alpha1.MySQLGrantTemplate{}, handler.EnqueueRequestsFromMapFunc(r.mysqlUsersForGrantTemplate)).
 		Complete(r)
 }
 
-// finalizeMySQLUser drops MySQL user
-func (r *MySQLUserReconciler) finalizeMySQLUser(ctx context.Context, mysqlClient *sql.DB, mysqlUser *mysqlv1alpha1.MySQLUser) error {
-	if mysqlUser.Status.UserCreated {
-		_, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("DROP USER IF EXISTS '%s'@'%s'", mysqlUser.Spec.Username, mysqlUser.Spec.Host))
+// mysqlUserForMySQLGrant enqueues the MySQLUser that the MySQLGrant references
+func mysqlUserForMySQLGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	mysqlGrant, ok := obj.(*mysqlv1alpha1.MySQLGrant)
+	if !ok || mysqlGrant.Spec.UserRef == nil {
+		return nil
+	}
+	namespace, name := mysqlGrant.GetUserRef()
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// mysqlUsersForGrantTemplate enqueues the MySQLUsers that reference the MySQLGrantTemplate
+func (r *MySQLUserReconciler) mysqlUsersForGrantTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	mysqlUserList := &mysqlv1alpha1.MySQLUserList{}
+	if err := r.List(ctx, mysqlUserList); err != nil {
+		log.FromContext(ctx).Error(err, "[GrantTemplate] Failed to list MySQLUsers", "mysqlGrantTemplate", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, mysqlUser := range mysqlUserList.Items {
+		for _, ref := range mysqlUser.Spec.GrantTemplates {
+			if ref.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mysqlUser.Namespace, Name: mysqlUser.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// planFinalizeMySQLUser publishes the statement that finalizeMySQLUser would
+// execute to the status and an Event without executing it
+func (r *MySQLUserReconciler) planFinalizeMySQLUser(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser) {
+	log := log.FromContext(ctx)
+	plan := []string{}
+	if mysqlUser.Status.UserCreated && mysqlUser.Status.Origin == mysqlv1alpha1.OriginCreated {
+		plan = append(plan, dropUserStatement(mysqlUser))
+	}
+	log.Info("[Plan] Planned statements", "userIdentity", mysqlUser.GetUserIdentity(), "statements", len(plan))
+	r.Recorder.Event(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonPlanned, formatPlan(plan))
+
+	mysqlUser.Status.Phase = mysqlUserPhasePlanned
+	mysqlUser.Status.Reason = mysqlUserReasonPlanned
+	mysqlUser.Status.Plan = plan
+	if serr := r.Status().Update(ctx, mysqlUser); serr != nil {
+		log.Error(serr, "Failed to update MySQLUser status", "mysqlUser", mysqlUser.Name)
+	}
+}
+
+func dropUserStatement(mysqlUser *mysqlv1alpha1.MySQLUser) string {
+	return fmt.Sprintf("DROP USER IF EXISTS '%s'@'%s'", mysqlUser.Spec.Username, mysqlUser.Spec.Host)
+}
+
+// finalizeMySQLUser drops MySQL user if it was created by the operator
+func (r *MySQLUserReconciler) finalizeMySQLUser(ctx context.Context, clusterKey string, mysqlClient *sql.DB, mysqlUser *mysqlv1alpha1.MySQLUser) error {
+	if mysqlUser.Status.UserCreated && mysqlUser.Status.Origin == mysqlv1alpha1.OriginCreated {
+		_, err := mysqlClient.ExecContext(ctx, dropUserStatement(mysqlUser))
 		if err != nil {
 			return err
 		}
+		r.invalidateGrants(clusterKey, mysqlUser.GetUserIdentity())
 		metrics.MysqlUserDeletedTotal.Increment()
+		r.Recorder.Eventf(mysqlUser, v1.EventTypeNormal, mysqlUserEventReasonDropped, "Dropped user %s", mysqlUser.GetUserIdentity())
 	}
 
 	return nil
 }
 
+// removeFinalizer removes mysqlUserFinalizer so that the object can be deleted
+func (r *MySQLUserReconciler) removeFinalizer(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser) error {
+	if controllerutil.RemoveFinalizer(mysqlUser, mysqlUserFinalizer) {
+		return r.Update(ctx, mysqlUser)
+	}
+	return nil
+}
+
 func (r *MySQLUserReconciler) ifOwnerReferencesContains(ownerReferences []metav1.OwnerReference, mysql *mysqlv1alpha1.MySQL) bool {
 	for _, ref := range ownerReferences {
 		if ref.APIVersion == "mysql.nakamasato.com/v1alpha1" && ref.Kind == "MySQL" && ref.UID == mysql.UID {
@@ -306,6 +662,25 @@ type Grant struct {
 	ComputeGroupPrivs  sql.NullString
 }
 
+// Dialect is the SQL flavor of the target cluster, detected from the
+// shape of the SHOW GRANTS output.
+type Dialect string
+
+const (
+	DialectDoris     Dialect = "doris"
+	DialectStarRocks Dialect = "starrocks"
+)
+
+// Doris expresses the grant option as a privilege instead of WITH GRANT OPTION.
+const dorisGrantPriv = "GRANT_PRIV"
+
+// Doris and StarRocks name their internal catalog differently.
+const (
+	dorisInternalCatalog    = "internal"
+	starRocksDefaultCatalog = "default_catalog"
+)
+
+// EntityType is the kind of column in Doris SHOW GRANTS
 type EntityType string
 
 const (
@@ -318,51 +693,60 @@ func (t EntityType) Equals(other EntityType) bool {
 	return t == other
 }
 
-type Entity struct {
-	Type EntityType
-	Name string
+// unquote removes quotes and backticks around an identifier
+func unquote(name string) string {
+	return strings.Trim(name, "`' ")
 }
 
-func (e Entity) IDString() string {
-	return fmt.Sprintf("%s:%s", e.Type, e.Name)
-}
-
-func (e Entity) SQLString() string {
-	switch e.Type {
+// dorisObject converts a target in Doris SHOW GRANTS into an object.
+// Table targets are catalog.db.table where * stands for everything.
+func dorisObject(name string, entityType EntityType) mysqlv1alpha1.ObjectRef {
+	switch entityType {
 	case Resource:
-		return fmt.Sprintf("RESOURCE '%s'", e.Name)
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: unquote(name)}
 	case WorkloadGroup:
-		return fmt.Sprintf("WORKLOAD GROUP '%s'", e.Name)
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindWorkloadGroup, Name: unquote(name)}
+	}
+
+	nameParts := strings.SplitN(strings.TrimSpace(name), ".", 3)
+	for len(nameParts) < 3 {
+		nameParts = append(nameParts, "*")
+	}
+	catalog, database, table := unquote(nameParts[0]), unquote(nameParts[1]), unquote(nameParts[2])
+	switch {
+	case catalog == "*":
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem}
+	case database == "*":
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindCatalog, Name: catalog}
+	case table == "*":
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: catalog, Name: database}
 	default:
-		return e.Name
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: catalog, Database: database, Name: table}
 	}
 }
 
-func (e Entity) Equals(other Entity) bool {
-	return e.Type == other.Type && e.Name == other.Name
-}
+// columnPrivilegeRegexp matches a privilege on columns, e.g. SELECT(a, b)
+var columnPrivilegeRegexp = regexp.MustCompile(`^([^(]*)\((.*)\)$`)
 
-func normalizeColumnOrder(perm string) string {
-	re := regexp.MustCompile(`^([^(]*)\((.*)\)$`)
-	// We may get inputs like
-	// 	SELECT(b,a,c)   -> SELECT(a,b,c)
-	// 	DELETE          -> DELETE
-	//  SELECT (a,b,c)  -> SELECT(a,b,c)
-	// if it's without parentheses, return it right away.
-	// Else split what is inside, sort it, concat together and return the result.
-	m := re.FindStringSubmatch(perm)
-	if m == nil || len(m) < 3 {
-		return perm
+// splitColumnPrivilege splits a privilege like SELECT(b,a) into SELECT and
+// its columns [a b]. A privilege without columns is returned as it is.
+func splitColumnPrivilege(perm string) (string, []string) {
+	m := columnPrivilegeRegexp.FindStringSubmatch(strings.TrimSpace(perm))
+	if m == nil {
+		return perm, nil
 	}
-
-	parts := strings.Split(m[2], ",")
-	for i := range parts {
-		parts[i] = strings.Trim(parts[i], "` ")
+	var columns []string
+	for _, column := range strings.Split(m[2], ",") {
+		columns = append(columns, normalizeColumn(column))
 	}
-	sort.Strings(parts)
-	precursor := strings.Trim(m[1], " ")
-	partsTogether := strings.Join(parts, ", ")
-	return fmt.Sprintf("%s(%s)", precursor, partsTogether)
+	sort.Strings(columns)
+	return strings.TrimSpace(m[1]), columns
+}
+
+// normalizeColumn returns the column name used for comparison.
+// Column names are case-insensitive.
+func normalizeColumn(column string) string {
+	return strings.ToLower(unquote(column))
 }
 
 func normalizePerms(perms []string) []string {
@@ -372,9 +756,7 @@ func normalizePerms(perms []string) []string {
 		permNorm := strings.Trim(perm, "` ")
 		permUcase := strings.ToUpper(permNorm)
 
-		permSortedColumns := normalizeColumnOrder(permUcase)
-
-		ret = append(ret, permSortedColumns)
+		ret = append(ret, permUcase)
 	}
 
 	// Sort permissions
@@ -388,46 +770,231 @@ func buildGrants(privs sql.NullString, entityType EntityType) ([]mysqlv1alpha1.G
 	if privs.Valid && privs.String != "" {
 		entries := strings.Split(privs.String, ";")
 		for _, entry := range entries {
-			var entity Entity = Entity{Type: entityType}
-			var privileges string
+			var name, privileges string
 			entryParts := strings.Split(entry, ":")
 			if len(entryParts) == 2 {
-				entity.Name = strings.TrimSpace(entryParts[0])
+				name = strings.TrimSpace(entryParts[0])
 				privileges = strings.TrimSpace(entryParts[1])
 			} else if len(entryParts) == 1 {
 				// If no target is specified, use global (*.*.*)
-				entity.Name = "*.*.*"
+				name = "*.*.*"
 				privileges = strings.TrimSpace(entryParts[0])
 			} else {
 				return nil, fmt.Errorf("invalid privilege format: %s", entry)
 			}
 
-			// Ensure that entity.Name matches the format *.*.* for the Table entity type
-			if entityType == Table {
-				nameParts := strings.Split(strings.TrimSpace(entity.Name), ".")
-				for len(nameParts) < 3 {
-					nameParts = append(nameParts, "*")
-				}
-				entity.Name = strings.Join(nameParts, ".")
-			}
-
+			perms, grantOption := splitGrantOption(normalizePerms(splitTopLevel(privileges, ',')))
 			grants = append(grants, mysqlv1alpha1.Grant{
-				Privileges: normalizePerms(strings.Split(privileges, ",")),
-				Target:     entity.SQLString(),
+				Privileges:  perms,
+				Object:      dorisObject(name, entityType),
+				GrantOption: grantOption,
 			})
 		}
 	}
 	return grants, nil
 }
 
-func fetchExistingGrants(ctx context.Context, mysqlClient *sql.DB, userIdentity string) ([]mysqlv1alpha1.Grant, error) {
+// splitGrantOption removes GRANT_PRIV from the given privileges and
+// returns true if it was present.
+func splitGrantOption(perms []string) ([]string, bool) {
+	ret := []string{}
+	grantOption := false
+	for _, perm := range perms {
+		if perm == dorisGrantPriv {
+			grantOption = true
+			continue
+		}
+		ret = append(ret, perm)
+	}
+	return ret, grantOption
+}
+
+var (
+	// e.g. GRANT SELECT, INSERT ON TABLE db1.tbl1 TO USER 'user'@'%' WITH GRANT OPTION
+	starRocksGrantRegexp = regexp.MustCompile(`(?i)^GRANT\s+(.+?)\s+ON\s+(.+?)\s+TO\s+(?:USER|ROLE)\s+.+?(\s+WITH\s+GRANT\s+OPTION)?\s*;?$`)
+	// Object types that StarRocks may list several objects for in one statement.
+	starRocksObjectRegexp = regexp.MustCompile(`(?i)^(CATALOG|DATABASE|TABLE|VIEW|MATERIALIZED VIEW|GLOBAL FUNCTION|FUNCTION|RESOURCE GROUP|RESOURCE|STORAGE VOLUME)\s+(.+)$`)
+	// e.g. ALL TABLES IN DATABASE db1, ALL TABLES IN ALL DATABASES, ALL DATABASES
+	starRocksAllObjectsRegexp = regexp.MustCompile(`(?i)^ALL\s+(CATALOGS|DATABASES|TABLES|VIEWS|MATERIALIZED\s+VIEWS|GLOBAL\s+FUNCTIONS|FUNCTIONS|RESOURCE\s+GROUPS|RESOURCES|STORAGE\s+VOLUMES)(?:\s+IN\s+(?:ALL\s+(DATABASES)|DATABASE\s+(.+)))?$`)
+)
+
+// parseStarRocksGrant converts one row of StarRocks SHOW GRANTS into grants.
+// Objects in a catalog get the catalog of the row unless qualified with one.
+// Role grants (without ON) and grants on other users (IMPERSONATE) are not
+// managed and return no grant.
+func parseStarRocksGrant(catalog, statement string) []mysqlv1alpha1.Grant {
+	m := starRocksGrantRegexp.FindStringSubmatch(strings.TrimSpace(statement))
+	if m == nil {
+		return nil
+	}
+	perms := normalizePerms(splitTopLevel(m[1], ','))
+	grantOption := m[3] != ""
+
+	var grants []mysqlv1alpha1.Grant
+	for _, object := range parseStarRocksObjects(m[2]) {
+		if object.Catalog == "" && isInCatalog(object) {
+			object.Catalog = catalog
+		}
+		grants = append(grants, mysqlv1alpha1.Grant{
+			Privileges:  perms,
+			Object:      object,
+			GrantOption: grantOption,
+		})
+	}
+	return grants
+}
+
+// parseStarRocksObjects converts the target of a StarRocks grant, which may
+// list several objects (e.g. "TABLE db1.tbl1, db1.tbl2"), into objects.
+func parseStarRocksObjects(target string) []mysqlv1alpha1.ObjectRef {
+	target = strings.TrimSpace(target)
+	if strings.EqualFold(target, string(mysqlv1alpha1.ObjectKindSystem)) {
+		return []mysqlv1alpha1.ObjectRef{{Kind: mysqlv1alpha1.ObjectKindSystem}}
+	}
+	if object, ok := parseStarRocksAllObjects(target); ok {
+		return []mysqlv1alpha1.ObjectRef{object}
+	}
+	m := starRocksObjectRegexp.FindStringSubmatch(target)
+	if m == nil {
+		return nil
+	}
+	objectType := strings.ToUpper(m[1])
+
+	var objects []mysqlv1alpha1.ObjectRef
+	for _, name := range splitTopLevel(m[2], ',') {
+		objects = append(objects, starRocksObject(objectType, name))
+	}
+	return objects
+}
+
+// parseStarRocksAllObjects converts a target like ALL TABLES IN DATABASE db1
+// into an object with "*" as the name, and "*" as the database for ALL DATABASES.
+func parseStarRocksAllObjects(target string) (mysqlv1alpha1.ObjectRef, bool) {
+	m := starRocksAllObjectsRegexp.FindStringSubmatch(target)
+	if m == nil {
+		return mysqlv1alpha1.ObjectRef{}, false
+	}
+	objectType := strings.TrimSuffix(strings.ToUpper(strings.Join(strings.Fields(m[1]), " ")), "S")
+	object := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKind(objectType), Name: mysqlv1alpha1.ObjectNameAll}
+	switch {
+	case objectType == "GLOBAL FUNCTION":
+		object.Kind = mysqlv1alpha1.ObjectKindFunction
+	case m[2] != "":
+		object.Database = mysqlv1alpha1.ObjectNameAll
+	case m[3] != "":
+		object.Database = unquote(m[3])
+	}
+	return object, true
+}
+
+// starRocksObject converts a (possibly qualified) object name of the given type into an object
+func starRocksObject(objectType, name string) mysqlv1alpha1.ObjectRef {
+	parts := splitTopLevel(name, '.')
+	for i := range parts {
+		parts[i] = unquote(parts[i])
+	}
+	switch mysqlv1alpha1.ObjectKind(objectType) {
+	case mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+		object := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKind(objectType), Name: parts[len(parts)-1]}
+		if len(parts) > 1 {
+			object.Database = parts[len(parts)-2]
+		}
+		if len(parts) > 2 {
+			object.Catalog = parts[len(parts)-3]
+		}
+		return object
+	case mysqlv1alpha1.ObjectKindDatabase:
+		object := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: parts[len(parts)-1]}
+		if len(parts) > 1 {
+			object.Catalog = parts[len(parts)-2]
+		}
+		return object
+	case mysqlv1alpha1.ObjectKindFunction:
+		object := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: parts[len(parts)-1]}
+		if len(parts) > 1 {
+			object.Database = parts[len(parts)-2]
+		}
+		return object
+	case "GLOBAL FUNCTION":
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: unquote(name)}
+	default:
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKind(objectType), Name: unquote(name)}
+	}
+}
+
+// isInCatalog returns true if the object belongs to a catalog, in which
+// StarRocks requires SET CATALOG before granting privileges on it.
+func isInCatalog(object mysqlv1alpha1.ObjectRef) bool {
+	switch object.Kind {
+	case mysqlv1alpha1.ObjectKindDatabase, mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+		return true
+	case mysqlv1alpha1.ObjectKindFunction:
+		return object.Database != ""
+	default:
+		return false
+	}
+}
+
+// splitTopLevel splits s by sep outside of parentheses, e.g. function arguments
+func splitTopLevel(s string, sep rune) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func scanStarRocksGrants(rows *sql.Rows) ([]mysqlv1alpha1.Grant, error) {
+	var grants []mysqlv1alpha1.Grant
+	for rows.Next() {
+		var userIdentity, catalog, statement sql.NullString
+		if err := rows.Scan(&userIdentity, &catalog, &statement); err != nil {
+			return nil, err
+		}
+		if !statement.Valid {
+			continue
+		}
+		grants = append(grants, parseStarRocksGrant(catalog.String, statement.String)...)
+	}
+	return grants, rows.Err()
+}
+
+// detectDialect detects the dialect from the SHOW GRANTS output of the current user
+func detectDialect(ctx context.Context, mysqlClient *sql.DB) (Dialect, error) {
+	rows, err := mysqlClient.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if len(columns) == 3 { // StarRocks: UserIdentity, Catalog, Grants
+		return DialectStarRocks, nil
+	}
+	return DialectDoris, nil
+}
+
+func fetchExistingGrants(ctx context.Context, mysqlClient *sql.DB, userIdentity string) ([]mysqlv1alpha1.Grant, Dialect, error) {
 	var grants []mysqlv1alpha1.Grant
 
 	log := log.FromContext(ctx)
 	rows, err := mysqlClient.QueryContext(ctx, fmt.Sprintf("SHOW GRANTS FOR %s;", userIdentity))
 	if err != nil {
 		log.Error(err, "[UserPrivs] Show grants failed")
-		return nil, err
+		return nil, "", err
 	}
 
 	defer rows.Close()
@@ -435,106 +1002,400 @@ func fetchExistingGrants(ctx context.Context, mysqlClient *sql.DB, userIdentity
 	columns, err := rows.Columns()
 	if err != nil {
 		log.Error(err, "[UserPrivs] Failed to get columns")
-		return nil, err
+		return nil, "", err
 	}
 
-	if rows.Next() {
-		var Grant Grant
-		var scanArgs []interface{}
-
-		if len(columns) == 11 { // Doris 2
-			scanArgs = []interface{}{
-				&Grant.UserIdentity,
-				&Grant.Comment,
-				&Grant.Password,
-				&Grant.Roles,
-				&Grant.GlobalPrivs,
-				&Grant.CatalogPrivs,
-				&Grant.DatabasePrivs,
-				&Grant.TablePrivs,
-				&Grant.ColPrivs,
-				&Grant.ResourcePrivs,
-				&Grant.WorkloadGroupPrivs,
-			}
-		} else if len(columns) == 15 { // Doris 3
-			scanArgs = []interface{}{
-				&Grant.UserIdentity,
-				&Grant.Comment,
-				&Grant.Password,
-				&Grant.Roles,
-				&Grant.GlobalPrivs,
-				&Grant.CatalogPrivs,
-				&Grant.DatabasePrivs,
-				&Grant.TablePrivs,
-				&Grant.ColPrivs,
-				&Grant.ResourcePrivs,
-				&Grant.CloudClusterPrivs,
-				&Grant.CloudStagePrivs,
-				&Grant.StorageVaultPrivs,
-				&Grant.WorkloadGroupPrivs,
-				&Grant.ComputeGroupPrivs,
-			}
-		} else {
-			log.Error(fmt.Errorf("unexpected number of columns"), "[UserPrivs] Unexpected number of columns", "columns", len(columns))
-			return nil, fmt.Errorf("unexpected number of columns: %d", len(columns))
+	if len(columns) == 3 { // StarRocks: UserIdentity, Catalog, Grants
+		grants, err := scanStarRocksGrants(rows)
+		if err != nil {
+			log.Error(err, "[UserPrivs] Read rows failed")
+			return nil, "", err
 		}
+		return grants, DialectStarRocks, nil
+	}
 
-		err := rows.Scan(scanArgs...)
+	if rows.Next() {
+		row, err := scanDorisGrant(rows, len(columns))
 		if err != nil {
 			log.Error(err, "[UserPrivs] Read row failed")
+			return nil, "", err
+		}
+		log.Info("[UserPrivs] Scanned row", "Grant", row)
+
+		if grants, err = buildDorisGrants(row); err != nil {
+			log.Error(err, "[UserPrivs] Build grants failed")
+			return nil, "", err
+		}
+	}
+	return grants, DialectDoris, nil
+}
+
+// scanDorisGrant reads one row of Doris SHOW GRANTS, whose columns depend on the version
+func scanDorisGrant(rows *sql.Rows, columns int) (Grant, error) {
+	var row Grant
+	var scanArgs []interface{}
+
+	if columns == 11 { // Doris 2
+		scanArgs = []interface{}{
+			&row.UserIdentity,
+			&row.Comment,
+			&row.Password,
+			&row.Roles,
+			&row.GlobalPrivs,
+			&row.CatalogPrivs,
+			&row.DatabasePrivs,
+			&row.TablePrivs,
+			&row.ColPrivs,
+			&row.ResourcePrivs,
+			&row.WorkloadGroupPrivs,
+		}
+	} else if columns == 15 { // Doris 3
+		scanArgs = []interface{}{
+			&row.UserIdentity,
+			&row.Comment,
+			&row.Password,
+			&row.Roles,
+			&row.GlobalPrivs,
+			&row.CatalogPrivs,
+			&row.DatabasePrivs,
+			&row.TablePrivs,
+			&row.ColPrivs,
+			&row.ResourcePrivs,
+			&row.CloudClusterPrivs,
+			&row.CloudStagePrivs,
+			&row.StorageVaultPrivs,
+			&row.WorkloadGroupPrivs,
+			&row.ComputeGroupPrivs,
+		}
+	} else {
+		return row, fmt.Errorf("unexpected number of columns: %d", columns)
+	}
+
+	err := rows.Scan(scanArgs...)
+	return row, err
+}
+
+// buildDorisGrants converts the privileges in a row of Doris SHOW GRANTS into grants
+func buildDorisGrants(row Grant) ([]mysqlv1alpha1.Grant, error) {
+	var grants []mysqlv1alpha1.Grant
+	entries := []struct {
+		privs      sql.NullString
+		entityType EntityType
+	}{
+		{row.GlobalPrivs, Table},
+		{row.CatalogPrivs, Table},
+		{row.DatabasePrivs, Table},
+		{row.TablePrivs, Table},
+		{row.ColPrivs, Table},
+		{row.ResourcePrivs, Resource},
+		{row.WorkloadGroupPrivs, WorkloadGroup},
+	}
+
+	for _, entry := range entries {
+		builtGrants, err := buildGrants(entry.privs, entry.entityType)
+		if err != nil {
 			return nil, err
 		}
+		grants = append(grants, builtGrants...)
+	}
+	return grants, nil
+}
 
-		log.Info("[UserPrivs] Scanned row", "Grant", Grant)
+// privileges returns the privilege list for GRANT/REVOKE statements.
+// Doris carries the grant option as GRANT_PRIV in the list itself.
+func (d Dialect) privileges(grant mysqlv1alpha1.Grant) string {
+	perms := columnPrivileges(grant)
+	if grant.GrantOption && d == DialectDoris {
+		perms = append(append([]string{}, perms...), dorisGrantPriv)
+	}
+	return strings.Join(perms, ",")
+}
 
-		entries := []struct {
-			privs      sql.NullString
-			entityType EntityType
-		}{
-			{Grant.GlobalPrivs, Table},
-			{Grant.CatalogPrivs, Table},
-			{Grant.DatabasePrivs, Table},
-			{Grant.TablePrivs, Table},
-			{Grant.ColPrivs, Table},
-			{Grant.ResourcePrivs, Resource},
-			{Grant.WorkloadGroupPrivs, WorkloadGroup},
+// columnPrivileges returns the privileges with the columns of the grant, e.g. SELECT(a,b)
+func columnPrivileges(grant mysqlv1alpha1.Grant) []string {
+	if len(grant.Columns) == 0 {
+		return grant.Privileges
+	}
+	perms := make([]string, 0, len(grant.Privileges))
+	for _, perm := range grant.Privileges {
+		perms = append(perms, fmt.Sprintf("%s(%s)", perm, strings.Join(grant.Columns, ",")))
+	}
+	return perms
+}
+
+// defaultCatalog returns the name of the internal catalog
+func (d Dialect) defaultCatalog() string {
+	if d == DialectDoris {
+		return dorisInternalCatalog
+	}
+	return starRocksDefaultCatalog
+}
+
+// normalizeObject converts an object to the form used for comparison,
+// where the internal catalog is always empty.
+func (d Dialect) normalizeObject(object mysqlv1alpha1.ObjectRef) mysqlv1alpha1.ObjectRef {
+	object.Kind = mysqlv1alpha1.ObjectKind(strings.ToUpper(strings.Join(strings.Fields(string(object.Kind)), " ")))
+	object.Catalog = unquote(object.Catalog)
+	object.Database = unquote(object.Database)
+	object.Name = unquote(object.Name)
+	if object.Catalog == d.defaultCatalog() {
+		object.Catalog = ""
+	}
+	if d == DialectDoris {
+		return normalizeDorisObject(object)
+	}
+	return object
+}
+
+// normalizeDorisObject converts an object to the level at which Doris keeps
+// the privileges, e.g. all tables in a database are the database itself.
+func normalizeDorisObject(object mysqlv1alpha1.ObjectRef) mysqlv1alpha1.ObjectRef {
+	// Doris manages privileges of views as tables
+	if object.Kind == mysqlv1alpha1.ObjectKindView || object.Kind == mysqlv1alpha1.ObjectKindMaterializedView {
+		object.Kind = mysqlv1alpha1.ObjectKindTable
+	}
+	catalog := object.Catalog
+	if catalog == "" {
+		catalog = dorisInternalCatalog
+	}
+	switch {
+	case object.Kind == mysqlv1alpha1.ObjectKindTable && object.Name == mysqlv1alpha1.ObjectNameAll && object.Database != mysqlv1alpha1.ObjectNameAll:
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: object.Catalog, Name: object.Database}
+	case object.Kind == mysqlv1alpha1.ObjectKindTable && object.Name == mysqlv1alpha1.ObjectNameAll,
+		object.Kind == mysqlv1alpha1.ObjectKindDatabase && object.Name == mysqlv1alpha1.ObjectNameAll:
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindCatalog, Name: catalog}
+	case object.Kind == mysqlv1alpha1.ObjectKindCatalog && object.Name == mysqlv1alpha1.ObjectNameAll:
+		return mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem}
+	}
+	return object
+}
+
+// normalizeGrants converts grants to the form used for comparison.
+// Privileges on columns, given either as columns or as e.g. SELECT(a,b),
+// are split into one grant per column so that columns are diffed one by one.
+func (d Dialect) normalizeGrants(grants []mysqlv1alpha1.Grant) []mysqlv1alpha1.Grant {
+	ret := []mysqlv1alpha1.Grant{}
+	for _, grant := range grants {
+		object := d.normalizeObject(grant.Object)
+		objectPerms := []string{}
+		columnPerms := map[string][]string{}
+		for _, perm := range grant.Privileges {
+			perm, columns := splitColumnPrivilege(perm)
+			if len(columns) == 0 {
+				for _, column := range grant.Columns {
+					columns = append(columns, normalizeColumn(column))
+				}
+			}
+			if len(columns) == 0 {
+				objectPerms = append(objectPerms, perm)
+				continue
+			}
+			for _, column := range columns {
+				columnPerms[column] = append(columnPerms[column], perm)
+			}
 		}
 
-		for _, entry := range entries {
-			if builtGrants, err := buildGrants(entry.privs, entry.entityType); err != nil {
-				log.Error(err, "[UserPrivs] Build grants failed")
-				return nil, err
-			} else {
-				grants = append(grants, builtGrants...)
+		columns := make([]string, 0, len(columnPerms))
+		for column := range columnPerms {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		if normalized, ok := d.normalizeGrant(object, objectPerms, nil, grant.GrantOption); ok {
+			ret = append(ret, normalized)
+		}
+		for _, column := range columns {
+			if normalized, ok := d.normalizeGrant(object, columnPerms[column], []string{column}, grant.GrantOption); ok {
+				ret = append(ret, normalized)
 			}
 		}
 	}
-	return grants, nil
+	return ret
+}
+
+// normalizeGrant builds a normalized grant, which is not ok without privileges
+func (d Dialect) normalizeGrant(object mysqlv1alpha1.ObjectRef, perms, columns []string, grantOption bool) (mysqlv1alpha1.Grant, bool) {
+	perms = normalizePerms(perms)
+	if d == DialectDoris {
+		var dorisGrantOption bool
+		perms, dorisGrantOption = splitGrantOption(perms)
+		grantOption = grantOption || dorisGrantOption
+	}
+	grant := mysqlv1alpha1.Grant{Privileges: perms, Object: object, Columns: columns, GrantOption: grantOption}
+	return grant, len(perms) > 0
+}
+
+// mergeColumnGrants merges grants that differ only in columns into one grant,
+// so that e.g. SELECT on columns a and b is granted in one statement.
+func mergeColumnGrants(grants []mysqlv1alpha1.Grant) []mysqlv1alpha1.Grant {
+	ret := []mysqlv1alpha1.Grant{}
+	merged := map[string]int{}
+	for _, grant := range grants {
+		if len(grant.Columns) == 0 {
+			ret = append(ret, grant)
+			continue
+		}
+		key := fmt.Sprintf("%s|%s", grantKey(mysqlv1alpha1.Grant{Object: grant.Object, GrantOption: grant.GrantOption}), strings.Join(grant.Privileges, ","))
+		if i, found := merged[key]; found {
+			columns := append(append([]string{}, ret[i].Columns...), grant.Columns...)
+			sort.Strings(columns)
+			ret[i].Columns = columns
+			continue
+		}
+		merged[key] = len(ret)
+		ret = append(ret, grant)
+	}
+	return ret
+}
+
+// objectSQL returns the object in the syntax of ON clause
+func (d Dialect) objectSQL(object mysqlv1alpha1.ObjectRef) string {
+	catalog := object.Catalog
+	if catalog == "" {
+		catalog = d.defaultCatalog()
+	}
+	if d == DialectDoris {
+		switch object.Kind {
+		case mysqlv1alpha1.ObjectKindSystem:
+			return "*.*.*"
+		case mysqlv1alpha1.ObjectKindCatalog:
+			return fmt.Sprintf("%s.*.*", object.Name)
+		case mysqlv1alpha1.ObjectKindDatabase:
+			return fmt.Sprintf("%s.%s.*", catalog, object.Name)
+		case mysqlv1alpha1.ObjectKindTable:
+			return fmt.Sprintf("%s.%s.%s", catalog, object.Database, object.Name)
+		case mysqlv1alpha1.ObjectKindResource, mysqlv1alpha1.ObjectKindWorkloadGroup:
+			return fmt.Sprintf("%s '%s'", object.Kind, object.Name)
+		}
+	}
+	if object.Name == mysqlv1alpha1.ObjectNameAll {
+		return allObjectsSQL(object)
+	}
+	switch object.Kind {
+	case mysqlv1alpha1.ObjectKindSystem:
+		return string(object.Kind)
+	case mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+		return fmt.Sprintf("%s %s.%s", object.Kind, object.Database, object.Name)
+	case mysqlv1alpha1.ObjectKindFunction:
+		if object.Database == "" {
+			return fmt.Sprintf("GLOBAL FUNCTION %s", object.Name)
+		}
+		return fmt.Sprintf("FUNCTION %s.%s", object.Database, object.Name)
+	default:
+		return fmt.Sprintf("%s %s", object.Kind, object.Name)
+	}
+}
+
+// catalogToSet returns the catalog to switch to before granting privileges
+// on the object, or empty if the statement can be executed as it is.
+// Doris qualifies objects with the catalog instead.
+func (d Dialect) catalogToSet(object mysqlv1alpha1.ObjectRef) string {
+	if d == DialectDoris || !isInCatalog(object) || object.Catalog == d.defaultCatalog() {
+		return ""
+	}
+	return object.Catalog
+}
+
+// allObjectsSQL returns the StarRocks syntax for all objects of the kind,
+// e.g. ALL TABLES IN DATABASE db1
+func allObjectsSQL(object mysqlv1alpha1.ObjectRef) string {
+	switch {
+	case object.Kind == mysqlv1alpha1.ObjectKindFunction && object.Database == "":
+		return "ALL GLOBAL FUNCTIONS"
+	case object.Database == mysqlv1alpha1.ObjectNameAll:
+		return fmt.Sprintf("ALL %sS IN ALL DATABASES", object.Kind)
+	case object.Database != "":
+		return fmt.Sprintf("ALL %sS IN DATABASE %s", object.Kind, object.Database)
+	default:
+		return fmt.Sprintf("ALL %sS", object.Kind)
+	}
+}
+
+func (d Dialect) grantStatement(userIdentity string, grant mysqlv1alpha1.Grant) string {
+	withGrantOption := ""
+	if grant.GrantOption && d != DialectDoris {
+		withGrantOption = " WITH GRANT OPTION"
+	}
+	return fmt.Sprintf("GRANT %s ON %s TO %s%s;", d.privileges(grant), d.objectSQL(grant.Object), userIdentity, withGrantOption)
+}
+
+func (d Dialect) revokeStatement(userIdentity string, grant mysqlv1alpha1.Grant) string {
+	return fmt.Sprintf("REVOKE %s ON %s FROM %s;", d.privileges(grant), d.objectSQL(grant.Object), userIdentity)
+}
+
+// grantee is the user or role that privileges are granted to, with the
+// object on which Events of the GRANT and REVOKE statements are recorded
+type grantee struct {
+	object   runtime.Object
+	identity string
 }
 
-func (r *MySQLUserReconciler) grantPrivileges(ctx context.Context, mysqlClient *sql.DB, userIdentity string, grant mysqlv1alpha1.Grant) error {
+func grantPrivileges(ctx context.Context, recorder record.EventRecorder, mysqlClient *sql.DB, dialect Dialect, to grantee, grant mysqlv1alpha1.Grant) error {
 	log := log.FromContext(ctx)
-	_, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("GRANT %s ON %s TO %s;", strings.Join(grant.Privileges, ","), grant.Target, userIdentity))
+	err := execInCatalog(ctx, mysqlClient, dialect.catalogToSet(grant.Object), dialect.grantStatement(to.identity, grant))
 	if err != nil {
+		recorder.Eventf(to.object, v1.EventTypeWarning, mysqlUserEventReasonFailedToGrant, "Failed to grant %s: %v", formatGrants([]mysqlv1alpha1.Grant{grant}), err)
 		return err
 	}
-	log.Info("[UserPrivs] Grant", "userIdentity", userIdentity, "privileges", grant.Privileges, "target", grant.Target)
+	log.Info("[UserPrivs] Grant", "grantee", to.identity, "privileges", grant.Privileges, "object", grant.Object.String(), "grantOption", grant.GrantOption)
+	recorder.Eventf(to.object, v1.EventTypeNormal, mysqlUserEventReasonGranted, "Granted %s", formatGrants([]mysqlv1alpha1.Grant{grant}))
 	return nil
 }
 
-func (r *MySQLUserReconciler) revokePrivileges(ctx context.Context, mysqlClient *sql.DB, userIdentity string, grants []mysqlv1alpha1.Grant) error {
+func revokePrivileges(ctx context.Context, recorder record.EventRecorder, mysqlClient *sql.DB, dialect Dialect, from grantee, grants []mysqlv1alpha1.Grant) error {
 	log := log.FromContext(ctx)
 	for _, grant := range grants {
-		_, err := mysqlClient.ExecContext(ctx, fmt.Sprintf("REVOKE %s ON %s FROM %s;", strings.Join(grant.Privileges, ","), grant.Target, userIdentity))
+		err := execInCatalog(ctx, mysqlClient, dialect.catalogToSet(grant.Object), dialect.revokeStatement(from.identity, grant))
 		if err != nil {
 			log.Error(err, "[UserPrivs] Revoke failed: %w", err)
+			recorder.Eventf(from.object, v1.EventTypeWarning, mysqlUserEventReasonFailedToGrant, "Failed to revoke %s: %v", formatGrants([]mysqlv1alpha1.Grant{grant}), err)
 			return err
 		}
-		log.Info("[UserPrivs] Revoke", "userIdentity", userIdentity, "privileges", grant.Privileges, "target", grant.Target)
+		log.Info("[UserPrivs] Revoke", "grantee", from.identity, "privileges", grant.Privileges, "object", grant.Object.String(), "grantOption", grant.GrantOption)
+		recorder.Eventf(from.object, v1.EventTypeNormal, mysqlUserEventReasonRevoked, "Revoked %s", formatGrants([]mysqlv1alpha1.Grant{grant}))
 	}
 	return nil
 }
 
+// execInCatalog executes the statement after switching to the catalog if given.
+// The catalog is switched back on the same connection before it returns to the
+// pool, or the connection is discarded if it can't be switched back.
+func execInCatalog(ctx context.Context, mysqlClient *sql.DB, catalog, statement string) error {
+	if catalog == "" {
+		_, err := mysqlClient.ExecContext(ctx, statement)
+		return err
+	}
+	conn, err := mysqlClient.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, setCatalogStatement(catalog)); err != nil {
+		discardConn(conn)
+		return err
+	}
+	_, err = conn.ExecContext(ctx, statement)
+	if _, resetErr := conn.ExecContext(ctx, setCatalogStatement(starRocksDefaultCatalog)); resetErr != nil {
+		// Other statements on the pool must not run in the catalog
+		discardConn(conn)
+		if err == nil {
+			err = resetErr
+		}
+	}
+	return err
+}
+
+// setCatalogStatement returns SET CATALOG with the quoted catalog
+func setCatalogStatement(catalog string) string {
+	return fmt.Sprintf("SET CATALOG `%s`", strings.ReplaceAll(catalog, "`", "``"))
+}
+
+// discardConn closes the connection instead of returning it to the pool,
+// e.g. when its session state can't be reset
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+}
+
 func comparePrivileges(oldPrivileges, newPrivileges []string) (revokePrivileges, addPrivileges []string) {
 	oldPrivilegeSet := make(map[string]struct{})
 	newPrivilegeSet := make(map[string]struct{})
@@ -562,32 +1423,55 @@ func comparePrivileges(oldPrivileges, newPrivileges []string) (revokePrivileges,
 	return revokePrivileges, addPrivileges
 }
 
+// grantKey identifies a grant by its object and grant option, so that
+// toggling the grant option revokes and re-grants the privileges.
+func grantKey(grant mysqlv1alpha1.Grant) string {
+	object := grant.Object
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%t", object.Kind, object.Catalog, object.Database, object.Name, strings.Join(grant.Columns, ","), grant.GrantOption)
+}
+
+// addToGrantMap adds the grant to the map by grantKey, merging the privileges
+// of grants on the same object, e.g. from a MySQLUser and MySQLGrants
+func addToGrantMap(grantMap map[string]mysqlv1alpha1.Grant, grant mysqlv1alpha1.Grant) {
+	key := grantKey(grant)
+	if existing, found := grantMap[key]; found {
+		privileges := append(append([]string{}, existing.Privileges...), grant.Privileges...)
+		sort.Strings(privileges)
+		grant.Privileges = slices.Compact(privileges)
+	}
+	grantMap[key] = grant
+}
+
 func calculateGrantDiff(oldGrants, newGrants []mysqlv1alpha1.Grant) (grantsToRevoke, grantsToAdd []mysqlv1alpha1.Grant) {
 	oldGrantMap := make(map[string]mysqlv1alpha1.Grant)
 	newGrantMap := make(map[string]mysqlv1alpha1.Grant)
 
 	for _, grant := range oldGrants {
-		oldGrantMap[grant.Target] = grant
+		addToGrantMap(oldGrantMap, grant)
 	}
 
 	for _, grant := range newGrants {
-		newGrantMap[grant.Target] = grant
+		addToGrantMap(newGrantMap, grant)
 	}
 
-	for target, oldGrant := range oldGrantMap {
-		if newGrant, found := newGrantMap[target]; found {
+	for key, oldGrant := range oldGrantMap {
+		if newGrant, found := newGrantMap[key]; found {
 			// Compare privileges and determine partial revocation and addition
 			revokePrivileges, addPrivileges := comparePrivileges(oldGrant.Privileges, newGrant.Privileges)
 			if len(revokePrivileges) > 0 {
 				grantsToRevoke = append(grantsToRevoke, mysqlv1alpha1.Grant{
-					Target:     oldGrant.Target,
-					Privileges: revokePrivileges,
+					Object:      oldGrant.Object,
+					Columns:     oldGrant.Columns,
+					Privileges:  revokePrivileges,
+					GrantOption: oldGrant.GrantOption,
 				})
 			}
 			if len(addPrivileges) > 0 {
 				grantsToAdd = append(grantsToAdd, mysqlv1alpha1.Grant{
-					Target:     newGrant.Target,
-					Privileges: addPrivileges,
+					Object:      newGrant.Object,
+					Columns:     newGrant.Columns,
+					Privileges:  addPrivileges,
+					GrantOption: newGrant.GrantOption,
 				})
 			}
 		} else {
@@ -595,8 +1479,8 @@ func calculateGrantDiff(oldGrants, newGrants []mysqlv1alpha1.Grant) (grantsToRev
 		}
 	}
 
-	for target, newGrant := range newGrantMap {
-		if _, found := oldGrantMap[target]; !found {
+	for key, newGrant := range newGrantMap {
+		if _, found := oldGrantMap[key]; !found {
 			grantsToAdd = append(grantsToAdd, newGrant)
 		}
 	}
@@ -604,34 +1488,256 @@ func calculateGrantDiff(oldGrants, newGrants []mysqlv1alpha1.Grant) (grantsToRev
 	return grantsToRevoke, grantsToAdd
 }
 
-func (r *MySQLUserReconciler) updateGrants(ctx context.Context, mysqlClient *sql.DB, userIdentity string, grants []mysqlv1alpha1.Grant) error {
+// diffGrants normalizes the existing grants and the grants in the spec to
+// the same form and returns the grants to revoke and the grants to add.
+func diffGrants(dialect Dialect, existingGrants, specGrants []mysqlv1alpha1.Grant) (grantsToRevoke, grantsToAdd []mysqlv1alpha1.Grant) {
+	grantsToRevoke, grantsToAdd = calculateGrantDiff(dialect.normalizeGrants(existingGrants), dialect.normalizeGrants(specGrants))
+	return mergeColumnGrants(grantsToRevoke), mergeColumnGrants(grantsToAdd)
+}
+
+// grantChange is a GRANT or a REVOKE of a grant
+type grantChange struct {
+	revoke bool
+	grant  mysqlv1alpha1.Grant
+}
+
+// inverse returns the change that undoes the change
+func (c grantChange) inverse() grantChange {
+	return grantChange{revoke: !c.revoke, grant: c.grant}
+}
+
+// orderGrantChanges orders the changes so that the user keeps the privileges
+// of both the existing and the new grants until all additions succeed:
+//  1. Revoke privileges that are granted again with another grant option,
+//     because REVOKE removes the privilege regardless of the grant option
+//  2. Add the new grants
+//  3. Revoke the other obsolete grants
+func orderGrantChanges(grantsToRevoke, grantsToAdd []mysqlv1alpha1.Grant) []grantChange {
+	sortGrants(grantsToRevoke)
+	sortGrants(grantsToAdd)
+
+	regranted := []grantChange{}
+	obsolete := []grantChange{}
+	for _, grant := range grantsToRevoke {
+		overlapping, rest := splitOverlappingPrivileges(grant, grantsToAdd)
+		if len(overlapping) > 0 {
+			regrant := grant
+			regrant.Privileges = overlapping
+			regranted = append(regranted, grantChange{revoke: true, grant: regrant})
+		}
+		if len(rest) > 0 {
+			grant.Privileges = rest
+			obsolete = append(obsolete, grantChange{revoke: true, grant: grant})
+		}
+	}
+
+	changes := regranted
+	for _, grant := range grantsToAdd {
+		changes = append(changes, grantChange{grant: grant})
+	}
+	return append(changes, obsolete...)
+}
+
+// splitOverlappingPrivileges splits the privileges of the grant into ones
+// that are also added on the same object and columns, and the others
+func splitOverlappingPrivileges(grant mysqlv1alpha1.Grant, grantsToAdd []mysqlv1alpha1.Grant) (overlapping, rest []string) {
+	added := map[string]struct{}{}
+	for _, add := range grantsToAdd {
+		if add.Object != grant.Object || !columnsOverlap(add.Columns, grant.Columns) {
+			continue
+		}
+		for _, priv := range add.Privileges {
+			added[priv] = struct{}{}
+		}
+	}
+	for _, priv := range grant.Privileges {
+		if _, found := added[priv]; found {
+			overlapping = append(overlapping, priv)
+		} else {
+			rest = append(rest, priv)
+		}
+	}
+	return overlapping, rest
+}
+
+func columnsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	for _, column := range a {
+		if slices.Contains(b, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortGrants sorts the grants to execute them in a stable order
+func sortGrants(grants []mysqlv1alpha1.Grant) {
+	sort.Slice(grants, func(i, j int) bool {
+		return grantKey(grants[i])+strings.Join(grants[i].Privileges, ",") < grantKey(grants[j])+strings.Join(grants[j].Privileges, ",")
+	})
+}
+
+// grantStatements returns the statements of the changes in the order of
+// execution, prefixed with SET CATALOG if needed.
+func grantStatements(dialect Dialect, userIdentity string, changes []grantChange) []string {
+	statements := []string{}
+	for _, change := range changes {
+		statements = append(statements, grantChangeStatement(dialect, userIdentity, change))
+	}
+	return statements
+}
+
+func grantChangeStatement(dialect Dialect, userIdentity string, change grantChange) string {
+	statement := dialect.grantStatement(userIdentity, change.grant)
+	if change.revoke {
+		statement = dialect.revokeStatement(userIdentity, change.grant)
+	}
+	return withCatalog(dialect.catalogToSet(change.grant.Object), statement)
+}
+
+// applyGrantChanges executes the changes in order and returns the executed
+// statements. If a change fails, the executed changes are undone in reverse
+// order to restore the previous grants.
+func applyGrantChanges(ctx context.Context, recorder record.EventRecorder, mysqlClient *sql.DB, dialect Dialect, to grantee, changes []grantChange) ([]string, error) {
+	log := log.FromContext(ctx)
+	applied := []grantChange{}
+	statements := []string{}
+	for _, change := range changes {
+		err := applyGrantChange(ctx, recorder, mysqlClient, dialect, to, change)
+		if err == nil {
+			applied = append(applied, change)
+			statements = append(statements, grantChangeStatement(dialect, to.identity, change))
+			continue
+		}
+
+		log.Info("[UserPrivs] Roll back grants", "grantee", to.identity, "changes", len(applied))
+		for i := len(applied) - 1; i >= 0; i-- {
+			undo := applied[i].inverse()
+			if rerr := applyGrantChange(ctx, recorder, mysqlClient, dialect, to, undo); rerr != nil {
+				log.Error(rerr, "[UserPrivs] Failed to roll back grants", "grantee", to.identity)
+				recorder.Eventf(to.object, v1.EventTypeWarning, mysqlUserEventReasonFailedToRollBack, "Failed to roll back %s: %v", formatGrants([]mysqlv1alpha1.Grant{undo.grant}), rerr)
+				return statements, err
+			}
+			statements = append(statements, grantChangeStatement(dialect, to.identity, undo))
+		}
+		if len(applied) > 0 {
+			recorder.Eventf(to.object, v1.EventTypeWarning, mysqlUserEventReasonRolledBack, "Rolled back %d changes of grants", len(applied))
+		}
+		return statements, err
+	}
+	return statements, nil
+}
+
+func applyGrantChange(ctx context.Context, recorder record.EventRecorder, mysqlClient *sql.DB, dialect Dialect, to grantee, change grantChange) error {
+	if change.revoke {
+		return revokePrivileges(ctx, recorder, mysqlClient, dialect, to, []mysqlv1alpha1.Grant{change.grant})
+	}
+	return grantPrivileges(ctx, recorder, mysqlClient, dialect, to, change.grant)
+}
+
+// withCatalog prefixes the statement with SET CATALOG for display
+func withCatalog(catalog, statement string) string {
+	if catalog == "" {
+		return statement
+	}
+	return fmt.Sprintf("%s; %s", setCatalogStatement(catalog), statement)
+}
+
+func (r *MySQLUserReconciler) updateGrants(ctx context.Context, clusterKey string, mysqlClient *sql.DB, mysqlUser *mysqlv1alpha1.MySQLUser, grants []mysqlv1alpha1.Grant, sources grantSources, driftPolicy mysqlv1alpha1.DriftPolicy) error {
+	userIdentity := mysqlUser.GetUserIdentity()
+
 	// Fetch existing grants
-	existingGrants, fetchErr := fetchExistingGrants(ctx, mysqlClient, userIdentity)
+	existingGrants, dialect, fetchErr := r.existingGrants(ctx, clusterKey, mysqlClient, userIdentity)
 	if fetchErr != nil {
 		return fetchErr
 	}
 
-	// Normalize grants
-	for i := range grants {
-		grants[i].Privileges = normalizePerms(grants[i].Privileges)
+	// Calculate grants to revoke and grants to add
+	grantsToRevoke, grantsToAdd := diffGrants(dialect, existingGrants, grants)
+
+	// Any difference from the spec, MySQLGrantTemplates and MySQLGrants that were already applied is a drift
+	observed := mysqlUser.Status.ObservedGeneration == mysqlUser.Generation && sources.observedBy(mysqlUser)
+	drifted := observed && (len(grantsToRevoke) > 0 || len(grantsToAdd) > 0)
+	if drifted {
+		r.reportDrift(ctx, mysqlUser, grantsToRevoke, grantsToAdd)
+		if driftPolicy == mysqlv1alpha1.DriftPolicyReport {
+			return nil
+		}
 	}
 
-	// Calculate grants to revoke and grants to add
-	grantsToRevoke, grantsToAdd := calculateGrantDiff(existingGrants, grants)
+	// Add missing grants before revoking obsolete grants, and roll back on failure
+	if len(grantsToRevoke) > 0 || len(grantsToAdd) > 0 {
+		statements, err := applyGrantChanges(ctx, r.Recorder, mysqlClient, dialect, grantee{object: mysqlUser, identity: userIdentity}, orderGrantChanges(grantsToRevoke, grantsToAdd))
+		r.invalidateGrants(clusterKey, userIdentity)
+		mysqlUser.Status.AppliedStatements = statements
+		if err != nil {
+			return err
+		}
+	}
 
-	// Revoke obsolete grants
-	revokeErr := r.revokePrivileges(ctx, mysqlClient, userIdentity, grantsToRevoke)
-	if revokeErr != nil {
-		return revokeErr
+	condition := metav1.Condition{
+		Type:    mysqlUserConditionDrifted,
+		Status:  metav1.ConditionFalse,
+		Reason:  mysqlUserConditionReasonInSync,
+		Message: "Grants are in sync with the spec",
+	}
+	if drifted {
+		condition.Reason = mysqlUserConditionReasonDriftCorrected
+		condition.Message = "Grants were restored to the spec"
 	}
+	meta.SetStatusCondition(&mysqlUser.Status.Conditions, condition)
 
-	// Grant missing grants
-	for _, grant := range grantsToAdd {
-		grantErr := r.grantPrivileges(ctx, mysqlClient, userIdentity, grant)
-		if grantErr != nil {
-			return grantErr
+	return nil
+}
+
+// existingGrants returns the grants of the user from the grant cache if the
+// user is in the snapshot of the cluster, and from SHOW GRANTS otherwise
+func (r *MySQLUserReconciler) existingGrants(ctx context.Context, clusterKey string, mysqlClient *sql.DB, userIdentity string) ([]mysqlv1alpha1.Grant, Dialect, error) {
+	if r.GrantCache != nil {
+		grants, dialect, ok, err := r.GrantCache.Grants(ctx, clusterKey, mysqlClient, userIdentity)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "[GrantCache] Failed to take snapshot of grants", "clusterKey", clusterKey)
+		} else if ok {
+			return grants, dialect, nil
 		}
 	}
+	return fetchExistingGrants(ctx, mysqlClient, userIdentity)
+}
 
-	return nil
+// invalidateGrants drops the user from the grant cache after the user or its grants are changed
+func (r *MySQLUserReconciler) invalidateGrants(clusterKey, userIdentity string) {
+	if r.GrantCache != nil {
+		r.GrantCache.Invalidate(clusterKey, userIdentity)
+	}
+}
+
+// reportDrift records grants that drifted from the spec as a condition, a metric and an Event
+func (r *MySQLUserReconciler) reportDrift(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser, grantsToRevoke, grantsToAdd []mysqlv1alpha1.Grant) {
+	log := log.FromContext(ctx)
+	message := fmt.Sprintf("Grants drifted from the spec: unexpected [%s], missing [%s]", formatGrants(grantsToRevoke), formatGrants(grantsToAdd))
+	log.Info("[MySQL] Detected drift of Grants", "userIdentity", mysqlUser.GetUserIdentity(), "revoke", len(grantsToRevoke), "add", len(grantsToAdd))
+
+	meta.SetStatusCondition(&mysqlUser.Status.Conditions, metav1.Condition{
+		Type:    mysqlUserConditionDrifted,
+		Status:  metav1.ConditionTrue,
+		Reason:  mysqlUserConditionReasonDriftDetected,
+		Message: message,
+	})
+	metrics.MysqlUserGrantDriftTotal.Increment()
+	r.Recorder.Event(mysqlUser, v1.EventTypeWarning, mysqlUserEventReasonGrantDrift, message)
+}
+
+// formatGrants returns a short description of grants, e.g. "SELECT,INSERT ON db.*"
+func formatGrants(grants []mysqlv1alpha1.Grant) string {
+	descriptions := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		description := fmt.Sprintf("%s ON %s", strings.Join(columnPrivileges(grant), ","), grant.Object)
+		if grant.GrantOption {
+			description += " WITH GRANT OPTION"
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, "; ")
 }
@@ -3,18 +3,25 @@ package controllers
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 
 	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
 	. "github.com/nakamasato/mysql-operator/internal/mysql"
@@ -39,6 +46,7 @@ var _ = Describe("MySQLUser controller", func() {
 				Client:       k8sManager.GetClient(),
 				Scheme:       k8sManager.GetScheme(),
 				MySQLClients: MySQLClients{fmt.Sprintf("%s-%s", Namespace, MySQLName): db},
+				Recorder:     k8sManager.GetEventRecorderFor("mysqluser-controller"),
 			}
 			err = ctrl.NewControllerManagedBy(k8sManager).
 				For(&mysqlv1alpha1.MySQLUser{}).
@@ -226,6 +234,7 @@ var _ = Describe("MySQLUser controller", func() {
 				Client:       k8sManager.GetClient(),
 				Scheme:       k8sManager.GetScheme(),
 				MySQLClients: MySQLClients{fmt.Sprintf("%s-%s", Namespace, MySQLName): db},
+				Recorder:     k8sManager.GetEventRecorderFor("mysqluser-controller"),
 			}
 			err = ctrl.NewControllerManagedBy(k8sManager).
 				For(&mysqlv1alpha1.MySQLUser{}).
@@ -407,3 +416,693 @@ var _ = Describe("MySQLUser controller", func() {
 		})
 	})
 })
+
+var _ = Describe("MySQLUser grants", func() {
+	It("Should parse WITH GRANT OPTION from StarRocks statements", func() {
+		grants := parseStarRocksGrant("default_catalog", "GRANT SELECT, INSERT ON TABLE db1.tbl1, db1.tbl2 TO USER 'user'@'%' WITH GRANT OPTION")
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"INSERT", "SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "default_catalog", Database: "db1", Name: "tbl1"}, GrantOption: true},
+			{Privileges: []string{"INSERT", "SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "default_catalog", Database: "db1", Name: "tbl2"}, GrantOption: true},
+		}))
+
+		grants = parseStarRocksGrant("default_catalog", "GRANT USAGE ON RESOURCE 'spark' TO USER 'user'@'%'")
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}},
+		}))
+
+		Expect(parseStarRocksGrant("default_catalog", "GRANT 'public' TO USER 'user'@'%'")).To(BeEmpty())
+	})
+
+	It("Should parse GRANT_PRIV from Doris output as grant option", func() {
+		grants, err := buildGrants(sql.NullString{String: "internal.db1: Select_priv,Grant_priv", Valid: true}, Table)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: "internal", Name: "db1"}, GrantOption: true},
+		}))
+	})
+
+	It("Should revoke and re-grant when grant option changes", func() {
+		oldGrants := []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}}}
+		newGrants := []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}, GrantOption: true}}
+
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(oldGrants, newGrants)
+		Expect(grantsToRevoke).To(Equal(oldGrants))
+		Expect(grantsToAdd).To(Equal(newGrants))
+
+		Expect(DialectStarRocks.grantStatement("'user'@'%'", newGrants[0])).To(Equal("GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%' WITH GRANT OPTION;"))
+		Expect(DialectDoris.grantStatement("'user'@'%'", newGrants[0])).To(Equal("GRANT SELECT,GRANT_PRIV ON internal.db1.tbl1 TO 'user'@'%';"))
+	})
+
+	It("Should normalize objects in the spec and SHOW GRANTS to the same form", func() {
+		specGrant := mysqlv1alpha1.Grant{Privileges: []string{"select"}, Object: mysqlv1alpha1.ObjectRef{Kind: "table", Database: "db1", Name: "tbl1"}}
+		serverGrant := parseStarRocksGrant("default_catalog", "GRANT SELECT ON TABLE default_catalog.db1.tbl1 TO USER 'user'@'%'")[0]
+		Expect(DialectStarRocks.normalizeGrants([]mysqlv1alpha1.Grant{serverGrant})).To(Equal(DialectStarRocks.normalizeGrants([]mysqlv1alpha1.Grant{specGrant})))
+
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(
+			DialectStarRocks.normalizeGrants([]mysqlv1alpha1.Grant{serverGrant}),
+			DialectStarRocks.normalizeGrants([]mysqlv1alpha1.Grant{specGrant}),
+		)
+		Expect(grantsToRevoke).To(BeEmpty())
+		Expect(grantsToAdd).To(BeEmpty())
+
+		// Doris pads table targets to catalog.db.table
+		serverGrants, err := buildGrants(sql.NullString{String: "internal.db1.tbl1: Select_priv", Valid: true}, Table)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(DialectDoris.normalizeGrants(serverGrants)).To(Equal(DialectDoris.normalizeGrants([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindView, Database: "db1", Name: "tbl1"}},
+		})))
+	})
+
+	It("Should take the catalog of objects from the SHOW GRANTS row", func() {
+		grants := parseStarRocksGrant("hive", "GRANT SELECT ON TABLE db1.tbl1 TO USER 'user'@'%'")
+		Expect(grants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "hive", Database: "db1", Name: "tbl1"}},
+		}))
+		Expect(DialectStarRocks.catalogToSet(grants[0].Object)).To(Equal("hive"))
+		Expect(DialectStarRocks.grantStatement("'user'@'%'", grants[0])).To(Equal("GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';"))
+		Expect(DialectDoris.catalogToSet(grants[0].Object)).To(BeEmpty())
+		Expect(DialectDoris.grantStatement("'user'@'%'", grants[0])).To(Equal("GRANT SELECT ON hive.db1.tbl1 TO 'user'@'%';"))
+
+		// The same table in another catalog is a different object
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(
+			DialectStarRocks.normalizeGrants(grants),
+			[]mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}}},
+		)
+		Expect(grantsToRevoke).To(HaveLen(1))
+		Expect(grantsToAdd).To(HaveLen(1))
+
+		// Catalog-level grants are not in a catalog
+		grants = parseStarRocksGrant("", "GRANT USAGE ON CATALOG hive TO USER 'user'@'%'")
+		Expect(grants[0].Object).To(Equal(mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindCatalog, Name: "hive"}))
+		Expect(DialectStarRocks.catalogToSet(grants[0].Object)).To(BeEmpty())
+		Expect(DialectStarRocks.catalogToSet(DialectStarRocks.normalizeObject(mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: "default_catalog", Name: "db1"}))).To(BeEmpty())
+	})
+
+	It("Should parse and render objects of each kind", func() {
+		for _, tc := range []struct {
+			target    string
+			object    mysqlv1alpha1.ObjectRef
+			starRocks string
+			doris     string
+		}{
+			{"SYSTEM", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem}, "SYSTEM", "*.*.*"},
+			{"CATALOG hive", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindCatalog, Name: "hive"}, "CATALOG hive", "hive.*.*"},
+			{"DATABASE `db1`", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: "db1"}, "DATABASE db1", "internal.db1.*"},
+			{"MATERIALIZED VIEW db1.mv1", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindMaterializedView, Database: "db1", Name: "mv1"}, "MATERIALIZED VIEW db1.mv1", ""},
+			{"FUNCTION db1.f(INT)", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Database: "db1", Name: "f(INT)"}, "FUNCTION db1.f(INT)", ""},
+			{"GLOBAL FUNCTION f(INT)", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: "f(INT)"}, "GLOBAL FUNCTION f(INT)", ""},
+			{"RESOURCE GROUP rg1", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResourceGroup, Name: "rg1"}, "RESOURCE GROUP rg1", ""},
+			{"STORAGE VOLUME sv1", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindStorageVolume, Name: "sv1"}, "STORAGE VOLUME sv1", ""},
+		} {
+			Expect(parseStarRocksObjects(tc.target)).To(Equal([]mysqlv1alpha1.ObjectRef{tc.object}), tc.target)
+			Expect(DialectStarRocks.objectSQL(tc.object)).To(Equal(tc.starRocks))
+			if tc.doris != "" {
+				Expect(DialectDoris.objectSQL(tc.object)).To(Equal(tc.doris))
+			}
+		}
+		Expect(DialectDoris.objectSQL(mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindWorkloadGroup, Name: "normal"})).To(Equal("WORKLOAD GROUP 'normal'"))
+		Expect(parseStarRocksObjects("USER 'other'@'%'")).To(BeEmpty())
+	})
+
+	It("Should diff column privileges per column", func() {
+		tbl1 := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "internal", Database: "db1", Name: "tbl1"}
+		serverGrants, err := buildGrants(sql.NullString{String: "internal.db1.tbl1: Select_priv(col1,Col2),Load_priv", Valid: true}, Table)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serverGrants).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"LOAD_PRIV", "SELECT_PRIV(COL1,COL2)"}, Object: tbl1},
+		}))
+
+		existing := DialectDoris.normalizeGrants(serverGrants)
+		tbl1.Catalog = ""
+		Expect(existing).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"LOAD_PRIV"}, Object: tbl1},
+			{Privileges: []string{"SELECT_PRIV"}, Object: tbl1, Columns: []string{"col1"}},
+			{Privileges: []string{"SELECT_PRIV"}, Object: tbl1, Columns: []string{"col2"}},
+		}))
+
+		desired := DialectDoris.normalizeGrants([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"LOAD_PRIV"}, Object: tbl1},
+			{Privileges: []string{"SELECT_PRIV"}, Object: tbl1, Columns: []string{"col1", "col3", "col4"}},
+		})
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(existing, desired)
+		grantsToRevoke, grantsToAdd = mergeColumnGrants(grantsToRevoke), mergeColumnGrants(grantsToAdd)
+		Expect(grantsToRevoke).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: tbl1, Columns: []string{"col2"}},
+		}))
+		Expect(grantsToAdd).To(Equal([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: tbl1, Columns: []string{"col3", "col4"}},
+		}))
+		Expect(DialectDoris.revokeStatement("'user'@'%'", grantsToRevoke[0])).To(Equal("REVOKE SELECT_PRIV(col2) ON internal.db1.tbl1 FROM 'user'@'%';"))
+		Expect(DialectDoris.grantStatement("'user'@'%'", grantsToAdd[0])).To(Equal("GRANT SELECT_PRIV(col3,col4) ON internal.db1.tbl1 TO 'user'@'%';"))
+	})
+
+	It("Should parse, render and diff grants on all objects", func() {
+		for _, tc := range []struct {
+			target string
+			object mysqlv1alpha1.ObjectRef
+		}{
+			{"ALL TABLES IN DATABASE db1", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "*"}},
+			{"ALL TABLES IN ALL DATABASES", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "*", Name: "*"}},
+			{"ALL DATABASES", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: "*"}},
+			{"ALL MATERIALIZED VIEWS IN DATABASE db1", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindMaterializedView, Database: "db1", Name: "*"}},
+			{"ALL GLOBAL FUNCTIONS", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Name: "*"}},
+			{"ALL RESOURCE GROUPS", mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResourceGroup, Name: "*"}},
+		} {
+			Expect(parseStarRocksObjects(tc.target)).To(Equal([]mysqlv1alpha1.ObjectRef{tc.object}), tc.target)
+			Expect(DialectStarRocks.objectSQL(tc.object)).To(Equal(tc.target))
+		}
+
+		// Read back as it was granted
+		existing := DialectStarRocks.normalizeGrants(parseStarRocksGrant("default_catalog", "GRANT SELECT ON ALL TABLES IN DATABASE db1 TO USER 'user'@'%'"))
+		desired := DialectStarRocks.normalizeGrants([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "*"}},
+		})
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(existing, desired)
+		Expect(grantsToRevoke).To(BeEmpty())
+		Expect(grantsToAdd).To(BeEmpty())
+
+		// Doris keeps all tables in a database as the database and all databases as the catalog
+		serverGrants, err := buildGrants(sql.NullString{String: "internal.db1: Select_priv; internal: Load_priv", Valid: true}, Table)
+		Expect(err).NotTo(HaveOccurred())
+		existing = DialectDoris.normalizeGrants(serverGrants)
+		desired = DialectDoris.normalizeGrants([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "*"}},
+			{Privileges: []string{"LOAD_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: "*"}},
+		})
+		grantsToRevoke, grantsToAdd = calculateGrantDiff(existing, desired)
+		Expect(grantsToRevoke).To(BeEmpty())
+		Expect(grantsToAdd).To(BeEmpty())
+		Expect(DialectDoris.objectSQL(desired[0].Object)).To(Equal("internal.db1.*"))
+		Expect(DialectDoris.objectSQL(desired[1].Object)).To(Equal("internal.*.*"))
+	})
+
+	It("Should resolve drift settings from MySQLUser and MySQL", func() {
+		mysql := &mysqlv1alpha1.MySQL{Spec: mysqlv1alpha1.MySQLSpec{
+			ResyncInterval: &metav1.Duration{Duration: 10 * time.Minute},
+			DriftPolicy:    mysqlv1alpha1.DriftPolicyReport,
+		}}
+		mysqlUser := mysqlv1alpha1.MySQLUser{}
+		Expect(mysqlUser.GetResyncInterval(nil)).To(BeZero())
+		Expect(mysqlUser.GetDriftPolicy(nil)).To(Equal(mysqlv1alpha1.DriftPolicyCorrect))
+		Expect(mysqlUser.GetResyncInterval(mysql)).To(Equal(10 * time.Minute))
+		Expect(mysqlUser.GetDriftPolicy(mysql)).To(Equal(mysqlv1alpha1.DriftPolicyReport))
+
+		mysqlUser.Spec.ResyncInterval = &metav1.Duration{Duration: time.Minute}
+		mysqlUser.Spec.DriftPolicy = mysqlv1alpha1.DriftPolicyCorrect
+		Expect(mysqlUser.GetResyncInterval(mysql)).To(Equal(time.Minute))
+		Expect(mysqlUser.GetDriftPolicy(mysql)).To(Equal(mysqlv1alpha1.DriftPolicyCorrect))
+	})
+
+	It("Should describe drifted grants", func() {
+		Expect(formatGrants([]mysqlv1alpha1.Grant{
+			{Privileges: []string{"SELECT", "INSERT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}},
+			{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}, GrantOption: true},
+		})).To(Equal("SELECT,INSERT ON TABLE db1.tbl1; USAGE ON RESOURCE spark WITH GRANT OPTION"))
+		Expect(formatGrants(nil)).To(BeEmpty())
+	})
+
+	It("Should add grants before revoking obsolete grants", func() {
+		table := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}
+		grantsToRevoke, grantsToAdd := calculateGrantDiff(
+			[]mysqlv1alpha1.Grant{{Privileges: []string{"INSERT", "SELECT"}, Object: table}},
+			[]mysqlv1alpha1.Grant{
+				{Privileges: []string{"SELECT"}, Object: table, GrantOption: true},
+				{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}},
+			},
+		)
+		changes := orderGrantChanges(grantsToRevoke, grantsToAdd)
+		// SELECT is revoked first because it is granted again WITH GRANT OPTION
+		Expect(grantStatements(DialectStarRocks, "'user'@'%'", changes)).To(Equal([]string{
+			"REVOKE SELECT ON TABLE db1.tbl1 FROM 'user'@'%';",
+			"GRANT USAGE ON RESOURCE spark TO 'user'@'%';",
+			"GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%' WITH GRANT OPTION;",
+			"REVOKE INSERT ON TABLE db1.tbl1 FROM 'user'@'%';",
+		}))
+		Expect(changes[1].inverse()).To(Equal(grantChange{revoke: true, grant: changes[1].grant}))
+	})
+
+	It("Should refuse to reconcile grants stored with target instead of object", func() {
+		db, err := sql.Open("testdbdriver", "test")
+		Expect(err).ToNot(HaveOccurred())
+		mysql := &mysqlv1alpha1.MySQL{ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "default"}}
+		mysqlUser := &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: mysqlv1alpha1.MySQLUserSpec{ClusterName: "starrocks", Username: "app", Host: "%", Grants: []mysqlv1alpha1.Grant{
+				{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: "db1"}},
+				{Privileges: []string{"SELECT"}}, // decoded from a grant with target only
+			}},
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &MySQLUserReconciler{
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, mysqlUser).WithStatusSubresource(mysqlUser).Build(),
+			Scheme:       scheme,
+			MySQLClients: MySQLClients{mysql.GetKey(): db},
+			Recorder:     recorder,
+		}
+
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Grants [1] of user 'app'@'%' have no object")))
+
+		updated := &mysqlv1alpha1.MySQLUser{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(mysqlUserPhaseNotReady))
+		Expect(updated.Status.Reason).To(Equal(mysqlUserReasonGrantWithoutObject))
+		Expect(updated.Finalizers).To(BeEmpty())
+	})
+
+	It("Should plan to drop the user when deleted in plan-only mode", func() {
+		mysql := &mysqlv1alpha1.MySQL{ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "default"}}
+		now := metav1.Now()
+		mysqlUser := &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", DeletionTimestamp: &now, Finalizers: []string{mysqlUserFinalizer}},
+			Spec:       mysqlv1alpha1.MySQLUserSpec{ClusterName: "starrocks", Username: "app", Host: "%", PlanOnly: true, DeletionPolicy: mysqlv1alpha1.DeletionPolicyDelete},
+			Status:     mysqlv1alpha1.MySQLUserStatus{UserCreated: true, Origin: mysqlv1alpha1.OriginCreated},
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &MySQLUserReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, mysqlUser).WithStatusSubresource(mysqlUser).Build(),
+			Scheme:   scheme,
+			Recorder: recorder,
+		}
+
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(Equal("Normal Planned Planned 1 statements: DROP USER IF EXISTS 'app'@'%'")))
+
+		// The finalizer is removed without dropping the user
+		err = reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), &mysqlv1alpha1.MySQLUser{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("MySQLUser events", func() {
+	It("Should record an Event for each GRANT and REVOKE", func() {
+		db, err := sql.Open("testdbdriver", "test")
+		Expect(err).ToNot(HaveOccurred())
+		recorder := record.NewFakeRecorder(10)
+		mysqlUser := &mysqlv1alpha1.MySQLUser{Spec: mysqlv1alpha1.MySQLUserSpec{Username: "user", Host: "%"}}
+		to := grantee{object: mysqlUser, identity: mysqlUser.GetUserIdentity()}
+
+		err = grantPrivileges(context.TODO(), recorder, db, DialectStarRocks, to, mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(<-recorder.Events).To(Equal("Normal Granted Granted SELECT ON TABLE db1.tbl1"))
+
+		err = revokePrivileges(context.TODO(), recorder, db, DialectStarRocks, to, []mysqlv1alpha1.Grant{
+			{Privileges: []string{"INSERT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}},
+			{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(<-recorder.Events).To(Equal("Normal Revoked Revoked INSERT ON TABLE db1.tbl1"))
+		Expect(<-recorder.Events).To(Equal("Normal Revoked Revoked USAGE ON RESOURCE spark"))
+	})
+
+	It("Should discard the connection if the catalog can't be switched back", func() {
+		db, fake := newFakeDB()
+		defer db.Close()
+
+		Expect(execInCatalog(context.TODO(), db, "hive", "GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';")).To(Succeed())
+		Expect(fake.Statements()).To(Equal([]string{
+			"SET CATALOG `hive`",
+			"GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';",
+			"SET CATALOG `default_catalog`",
+		}))
+		Expect(fake.Closed()).To(Equal(0))
+
+		fake.failOn = func(statement string) error {
+			if statement == "SET CATALOG `default_catalog`" {
+				return context.DeadlineExceeded
+			}
+			return nil
+		}
+		err := execInCatalog(context.TODO(), db, "hive", "GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(fake.Closed()).To(Equal(1))
+	})
+
+	It("Should record the applied statements", func() {
+		db, err := sql.Open("testdbdriver", "test")
+		Expect(err).ToNot(HaveOccurred())
+		to := grantee{object: &mysqlv1alpha1.MySQLUser{}, identity: "'user'@'%'"}
+
+		statements, err := applyGrantChanges(context.TODO(), record.NewFakeRecorder(10), db, DialectStarRocks, to, []grantChange{
+			{grant: mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}}},
+			{revoke: true, grant: mysqlv1alpha1.Grant{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(Equal([]string{
+			"GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';",
+			"REVOKE USAGE ON RESOURCE spark FROM 'user'@'%';",
+		}))
+	})
+
+	Context("When a later change fails", func() {
+		var db *sql.DB
+		var database *fakeDB
+		var recorder *record.FakeRecorder
+		var changes []grantChange
+		to := grantee{object: &mysqlv1alpha1.MySQLUser{}, identity: "'user'@'%'"}
+		failOn := func(failing ...string) func(string) error {
+			return func(statement string) error {
+				if slices.Contains(failing, statement) {
+					return context.DeadlineExceeded
+				}
+				return nil
+			}
+		}
+
+		BeforeEach(func() {
+			db, database = newFakeDB()
+			recorder = record.NewFakeRecorder(10)
+			changes = []grantChange{
+				{grant: mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}}},
+				{revoke: true, grant: mysqlv1alpha1.Grant{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Name: "spark"}}},
+				{grant: mysqlv1alpha1.Grant{Privileges: []string{"INSERT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl2"}}},
+			}
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		It("Should roll back the applied changes in reverse order", func() {
+			database.failOn = failOn("GRANT INSERT ON TABLE db1.tbl2 TO 'user'@'%';")
+
+			statements, err := applyGrantChanges(context.TODO(), recorder, db, DialectStarRocks, to, changes)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			rolledBack := []string{
+				"GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';",
+				"REVOKE USAGE ON RESOURCE spark FROM 'user'@'%';",
+				"GRANT USAGE ON RESOURCE spark TO 'user'@'%';",
+				"REVOKE SELECT ON TABLE db1.tbl1 FROM 'user'@'%';",
+			}
+			Expect(database.Statements()).To(Equal(rolledBack))
+			Expect(statements).To(Equal(rolledBack))
+
+			Expect(<-recorder.Events).To(Equal("Normal Granted Granted SELECT ON TABLE db1.tbl1"))
+			Expect(<-recorder.Events).To(Equal("Normal Revoked Revoked USAGE ON RESOURCE spark"))
+			Expect(<-recorder.Events).To(HavePrefix("Warning FailedToGrant Failed to grant INSERT ON TABLE db1.tbl2"))
+			Expect(<-recorder.Events).To(Equal("Normal Granted Granted USAGE ON RESOURCE spark"))
+			Expect(<-recorder.Events).To(Equal("Normal Revoked Revoked SELECT ON TABLE db1.tbl1"))
+			Expect(<-recorder.Events).To(Equal("Warning RolledBackGrants Rolled back 2 changes of grants"))
+		})
+
+		It("Should stop rolling back at the first inverse change that fails", func() {
+			database.failOn = failOn("GRANT INSERT ON TABLE db1.tbl2 TO 'user'@'%';", "GRANT USAGE ON RESOURCE spark TO 'user'@'%';")
+
+			statements, err := applyGrantChanges(context.TODO(), recorder, db, DialectStarRocks, to, changes)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			applied := []string{
+				"GRANT SELECT ON TABLE db1.tbl1 TO 'user'@'%';",
+				"REVOKE USAGE ON RESOURCE spark FROM 'user'@'%';",
+			}
+			Expect(database.Statements()).To(Equal(applied))
+			Expect(statements).To(Equal(applied))
+
+			Expect(<-recorder.Events).To(Equal("Normal Granted Granted SELECT ON TABLE db1.tbl1"))
+			Expect(<-recorder.Events).To(Equal("Normal Revoked Revoked USAGE ON RESOURCE spark"))
+			Expect(<-recorder.Events).To(HavePrefix("Warning FailedToGrant Failed to grant INSERT ON TABLE db1.tbl2"))
+			Expect(<-recorder.Events).To(HavePrefix("Warning FailedToGrant Failed to grant USAGE ON RESOURCE spark"))
+			Expect(<-recorder.Events).To(HavePrefix("Warning FailedToRollBackGrants Failed to roll back USAGE ON RESOURCE spark"))
+			Expect(recorder.Events).NotTo(Receive())
+		})
+	})
+})
+
+var _ = Describe("MySQLUser plan", func() {
+	var mysql *mysqlv1alpha1.MySQL
+	var secret *v1.Secret
+	var db *sql.DB
+	var database *fakeDB
+	var recorder *record.FakeRecorder
+	var mysqlUser *mysqlv1alpha1.MySQLUser
+	plan := func() (*mysqlv1alpha1.MySQLUser, string) {
+		reconciler := &MySQLUserReconciler{
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, secret, mysqlUser).WithStatusSubresource(mysqlUser).Build(),
+			Scheme:       scheme,
+			MySQLClients: MySQLClients{mysql.GetKey(): db},
+			Recorder:     recorder,
+		}
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := &mysqlv1alpha1.MySQLUser{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(mysqlUserPhasePlanned))
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		return updated, event
+	}
+
+	BeforeEach(func() {
+		mysql = &mysqlv1alpha1.MySQL{ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "default"}}
+		secret = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-password", Namespace: "default"}, Data: map[string][]byte{"password": []byte("secret")}}
+		db, database = newFakeDB()
+		database.rows = func(query string) ([]string, [][]driver.Value) {
+			return []string{"UserIdentity", "Catalog", "Grants"}, [][]driver.Value{
+				{"'app'@'%'", "default_catalog", "GRANT INSERT ON TABLE db1.tbl1 TO USER 'app'@'%'"},
+			}
+		}
+		recorder = record.NewFakeRecorder(10)
+		mysqlUser = &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: mysqlv1alpha1.MySQLUserSpec{
+				ClusterName: "starrocks",
+				Username:    "app",
+				Host:        "%",
+				SecretRef:   mysqlv1alpha1.SecretRef{Name: "app-password", Key: "password"},
+				PlanOnly:    true,
+				Grants:      []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "hive", Database: "db1", Name: "tbl1"}}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("Should plan to create the user without leaking the password", func() {
+		database.failOn = func(statement string) error {
+			if statement == "SHOW GRANTS FOR 'app'@'%'" {
+				return fmt.Errorf("user 'app'@'%%' doesn't exist")
+			}
+			return nil
+		}
+
+		updated, event := plan()
+		Expect(database.Statements()).To(BeEmpty())
+		Expect(updated.Status.Plan).To(Equal([]string{
+			"CREATE USER IF NOT EXISTS 'app'@'%' IDENTIFIED BY '****'",
+			"SET CATALOG `hive`; GRANT SELECT ON TABLE db1.tbl1 TO 'app'@'%';",
+		}))
+		Expect(event).To(HavePrefix("Normal Planned Planned 2 statements: CREATE USER IF NOT EXISTS 'app'@'%' IDENTIFIED BY '****'"))
+		Expect(event).NotTo(ContainSubstring("secret"))
+	})
+
+	It("Should plan to update an existing user without leaking the password", func() {
+		updated, event := plan()
+		// Only the user is looked up
+		Expect(database.Statements()).To(Equal([]string{"SHOW GRANTS FOR 'app'@'%'"}))
+		Expect(updated.Status.Plan).To(Equal([]string{
+			"ALTER USER 'app'@'%' IDENTIFIED BY '****'",
+			"SET CATALOG `hive`; GRANT SELECT ON TABLE db1.tbl1 TO 'app'@'%';",
+			"REVOKE INSERT ON TABLE db1.tbl1 FROM 'app'@'%';",
+		}))
+		Expect(event).To(HavePrefix("Normal Planned Planned 3 statements: "))
+		Expect(event).NotTo(ContainSubstring("secret"))
+	})
+
+	It("Should plan nothing for an adopted read-only user", func() {
+		mysqlUser.Spec.AdoptionPolicy = mysqlv1alpha1.AdoptionPolicyAdoptReadOnly
+		mysqlUser.Status.Origin = mysqlv1alpha1.OriginAdopted
+
+		updated, event := plan()
+		Expect(database.Statements()).To(Equal([]string{"SHOW GRANTS FOR 'app'@'%'"}))
+		Expect(updated.Status.Plan).To(BeEmpty())
+		Expect(event).To(Equal("Normal Planned No statements planned"))
+	})
+})
+
+var _ = Describe("MySQLUser drift", func() {
+	var db *sql.DB
+	var database *fakeDB
+	var recorder *record.FakeRecorder
+	var reconciler *MySQLUserReconciler
+	var mysqlUser *mysqlv1alpha1.MySQLUser
+	grants := []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}}}
+	sources := grantSources{mysqlGrants: []string{}, grantTemplates: []string{}}
+	driftTotal := func() float64 {
+		families, err := ctrlmetrics.Registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		for _, family := range families {
+			if family.GetName() == "mysqloperator_mysql_user_grant_drift_total" {
+				return family.GetMetric()[0].GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+
+	BeforeEach(func() {
+		db, database = newFakeDB()
+		// INSERT was granted and SELECT was revoked outside of the operator
+		database.rows = func(query string) ([]string, [][]driver.Value) {
+			return []string{"UserIdentity", "Catalog", "Grants"}, [][]driver.Value{
+				{"'app'@'%'", "default_catalog", "GRANT INSERT ON TABLE db1.tbl1 TO USER 'app'@'%'"},
+			}
+		}
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &MySQLUserReconciler{Recorder: recorder}
+		// The grants were already applied to the generation
+		mysqlUser = &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Generation: 2},
+			Spec:       mysqlv1alpha1.MySQLUserSpec{Username: "app", Host: "%", Grants: grants},
+			Status:     mysqlv1alpha1.MySQLUserStatus{ObservedGeneration: 2},
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("Should only report the drift with Report", func() {
+		before := driftTotal()
+
+		Expect(reconciler.updateGrants(context.TODO(), "default-starrocks", db, mysqlUser, grants, sources, mysqlv1alpha1.DriftPolicyReport)).To(Succeed())
+		Expect(database.Statements()).To(BeEmpty())
+		Expect(mysqlUser.Status.AppliedStatements).To(BeEmpty())
+
+		condition := meta.FindStatusCondition(mysqlUser.Status.Conditions, mysqlUserConditionDrifted)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(mysqlUserConditionReasonDriftDetected))
+		Expect(driftTotal()).To(Equal(before + 1))
+		Expect(recorder.Events).To(Receive(Equal("Warning GrantDrift Grants drifted from the spec: unexpected [INSERT ON TABLE db1.tbl1], missing [SELECT ON TABLE db1.tbl1]")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("Should restore the grants of the spec with Correct", func() {
+		before := driftTotal()
+
+		Expect(reconciler.updateGrants(context.TODO(), "default-starrocks", db, mysqlUser, grants, sources, mysqlv1alpha1.DriftPolicyCorrect)).To(Succeed())
+		Expect(database.Statements()).To(Equal([]string{
+			"GRANT SELECT ON TABLE db1.tbl1 TO 'app'@'%';",
+			"REVOKE INSERT ON TABLE db1.tbl1 FROM 'app'@'%';",
+		}))
+		Expect(mysqlUser.Status.AppliedStatements).To(Equal(database.Statements()))
+
+		condition := meta.FindStatusCondition(mysqlUser.Status.Conditions, mysqlUserConditionDrifted)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(mysqlUserConditionReasonDriftCorrected))
+		Expect(driftTotal()).To(Equal(before + 1))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning GrantDrift Grants drifted from the spec")))
+		Expect(recorder.Events).To(Receive(Equal("Normal Granted Granted SELECT ON TABLE db1.tbl1")))
+		Expect(recorder.Events).To(Receive(Equal("Normal Revoked Revoked INSERT ON TABLE db1.tbl1")))
+	})
+})
+
+var _ = Describe("MySQLUser adoption", func() {
+	var mysql *mysqlv1alpha1.MySQL
+	var secret *v1.Secret
+	var db *sql.DB
+	var database *fakeDB
+	var recorder *record.FakeRecorder
+	newReconciler := func(mysqlUser *mysqlv1alpha1.MySQLUser) *MySQLUserReconciler {
+		return &MySQLUserReconciler{
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql, secret, mysqlUser).WithStatusSubresource(mysqlUser).Build(),
+			Scheme:       scheme,
+			MySQLClients: MySQLClients{mysql.GetKey(): db},
+			Recorder:     recorder,
+		}
+	}
+	newMySQLUser := func(adoptionPolicy mysqlv1alpha1.AdoptionPolicy) *mysqlv1alpha1.MySQLUser {
+		return &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: mysqlv1alpha1.MySQLUserSpec{
+				ClusterName:    "starrocks",
+				Username:       "app",
+				Host:           "%",
+				SecretRef:      mysqlv1alpha1.SecretRef{Name: "app-password", Key: "password"},
+				AdoptionPolicy: adoptionPolicy,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		mysql = &mysqlv1alpha1.MySQL{ObjectMeta: metav1.ObjectMeta{Name: "starrocks", Namespace: "default"}}
+		secret = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-password", Namespace: "default"}, Data: map[string][]byte{"password": []byte("secret")}}
+		db, database = newFakeDB()
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("Should not touch an existing user with FailIfExists", func() {
+		mysqlUser := newMySQLUser(mysqlv1alpha1.AdoptionPolicyFailIfExists)
+		reconciler := newReconciler(mysqlUser)
+
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(database.Statements()).To(Equal([]string{"SHOW GRANTS FOR 'app'@'%'"}))
+
+		updated := &mysqlv1alpha1.MySQLUser{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(mysqlUserPhaseNotReady))
+		Expect(updated.Status.Reason).To(Equal(mysqlUserReasonAlreadyExists))
+		Expect(updated.Status.Origin).To(BeEmpty())
+	})
+
+	It("Should adopt an existing user without changing it with AdoptReadOnly", func() {
+		mysqlUser := newMySQLUser(mysqlv1alpha1.AdoptionPolicyAdoptReadOnly)
+		mysqlUser.Spec.Grants = []mysqlv1alpha1.Grant{{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Name: "db1"}}}
+		reconciler := newReconciler(mysqlUser)
+
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(database.Statements()).To(Equal([]string{"SHOW GRANTS FOR 'app'@'%'"}))
+		Expect(recorder.Events).To(Receive(Equal("Normal AdoptedUser Adopted existing user 'app'@'%'")))
+
+		updated := &mysqlv1alpha1.MySQLUser{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(mysqlUserPhaseReady))
+		Expect(updated.Status.Reason).To(Equal(mysqlUserReasonAdoptedReadOnly))
+		Expect(updated.Status.Origin).To(Equal(mysqlv1alpha1.OriginAdopted))
+	})
+
+	It("Should record the origin before creating the user", func() {
+		mysqlUser := newMySQLUser(mysqlv1alpha1.AdoptionPolicyFailIfExists)
+		reconciler := newReconciler(mysqlUser)
+		database.failOn = func(statement string) error {
+			if strings.HasPrefix(statement, "SHOW GRANTS") || strings.HasPrefix(statement, "CREATE USER") {
+				return context.DeadlineExceeded
+			}
+			return nil
+		}
+
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		// The user might have been created, and is not adopted by the next reconcile
+		updated := &mysqlv1alpha1.MySQLUser{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(mysqlUser), updated)).To(Succeed())
+		Expect(updated.Status.Reason).To(Equal(mysqlUserReasonMySQLFailedToCreateUser))
+		Expect(updated.Status.Origin).To(Equal(mysqlv1alpha1.OriginCreated))
+	})
+
+	It("Should drop a user created by an operator that didn't record the origin", func() {
+		now := metav1.Now()
+		mysqlUser := newMySQLUser("")
+		mysqlUser.DeletionTimestamp = &now
+		mysqlUser.Finalizers = []string{mysqlUserFinalizer}
+		mysqlUser.Spec.DeletionPolicy = mysqlv1alpha1.DeletionPolicyDelete
+		mysqlUser.Status = mysqlv1alpha1.MySQLUserStatus{UserCreated: true}
+		reconciler := newReconciler(mysqlUser)
+
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(mysqlUser)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(database.Statements()).To(Equal([]string{"DROP USER IF EXISTS 'app'@'%'"}))
+		Expect(recorder.Events).To(Receive(Equal("Normal DroppedUser Dropped user 'app'@'%'")))
+	})
+})
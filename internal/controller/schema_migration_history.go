@@ -0,0 +1,138 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+const (
+	// Applied migrations kept in the status
+	schemaMigrationHistoryLimit = 20
+
+	mysqlDBConditionSchemaMigrationVerified = "SchemaMigrationVerified"
+	mysqlDBConditionReasonChecksumsMatch    = "ChecksumsMatch"
+	mysqlDBConditionReasonChecksumMismatch  = "ChecksumMismatch"
+)
+
+// errMigrationsModified is returned when a migration file is modified in the source after it is applied
+var errMigrationsModified = errors.New("applied migration files are modified in the source")
+
+// nextVersion returns the version in the source after the current version, or the first version for 0
+func nextVersion(sourceDriver source.Driver, current uint) (uint, error) {
+	if current == 0 {
+		return sourceDriver.First()
+	}
+	return sourceDriver.Next(current)
+}
+
+// migrationChecksum returns the SHA-256 and the description of the up migration file of the version
+func migrationChecksum(sourceDriver source.Driver, version uint) (string, string, error) {
+	r, description, err := sourceDriver.ReadUp(version)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), description, nil
+}
+
+// recordAppliedMigration adds the migration of the version applied since start
+// to the history, dropping the oldest ones over the limit
+func recordAppliedMigration(mysqlDB *mysqlv1alpha1.MySQLDB, sourceDriver source.Driver, version uint, start time.Time) error {
+	checksum, description, err := migrationChecksum(sourceDriver, version)
+	if err != nil {
+		return err
+	}
+	history := append(mysqlDB.Status.SchemaMigrationHistory, mysqlv1alpha1.AppliedMigration{
+		Version:     version,
+		Description: description,
+		AppliedAt:   metav1.NewTime(start),
+		Duration:    time.Since(start).Round(time.Millisecond).String(),
+		Checksum:    checksum,
+	})
+	if len(history) > schemaMigrationHistoryLimit {
+		history = history[len(history)-schemaMigrationHistoryLimit:]
+	}
+	mysqlDB.Status.SchemaMigrationHistory = history
+	return nil
+}
+
+// forgetMigrationsAfter drops the migrations after the version from the
+// history, which are no longer applied after migrating down or forcing the version
+func forgetMigrationsAfter(mysqlDB *mysqlv1alpha1.MySQLDB, version uint) {
+	history := mysqlDB.Status.SchemaMigrationHistory[:0]
+	for _, applied := range mysqlDB.Status.SchemaMigrationHistory {
+		if applied.Version <= version {
+			history = append(history, applied)
+		}
+	}
+	mysqlDB.Status.SchemaMigrationHistory = history
+}
+
+// verifyAppliedMigrations compares the checksums of the migrations in the
+// history with the files in the source, and sets the SchemaMigrationVerified
+// condition. It returns errMigrationsModified if a file is modified or removed.
+func verifyAppliedMigrations(mysqlDB *mysqlv1alpha1.MySQLDB, sourceDriver source.Driver) error {
+	var modified []string
+	for _, applied := range mysqlDB.Status.SchemaMigrationHistory {
+		checksum, _, err := migrationChecksum(sourceDriver, applied.Version)
+		if errors.Is(err, os.ErrNotExist) {
+			modified = append(modified, fmt.Sprintf("%d (removed)", applied.Version))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if checksum != applied.Checksum {
+			modified = append(modified, fmt.Sprint(applied.Version))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               mysqlDBConditionSchemaMigrationVerified,
+		Status:             metav1.ConditionTrue,
+		Reason:             mysqlDBConditionReasonChecksumsMatch,
+		Message:            "Applied migration files are not modified in the source",
+		ObservedGeneration: mysqlDB.Generation,
+	}
+	var err error
+	if len(modified) > 0 {
+		err = fmt.Errorf("%w: version %s", errMigrationsModified, strings.Join(modified, ", "))
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = mysqlDBConditionReasonChecksumMismatch
+		condition.Message = fmt.Sprintf("Applied migration files are modified in the source: version %s. Restore them to migrate again.", strings.Join(modified, ", "))
+	}
+	meta.SetStatusCondition(&mysqlDB.Status.Conditions, condition)
+	return err
+}
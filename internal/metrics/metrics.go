@@ -32,8 +32,26 @@ var (
 		},
 	)
 
-	MysqlUserCreatedTotal *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: userCreatedTotal}
-	MysqlUserDeletedTotal *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: mysqlUserDeletedTotal}
+	mysqlUserGrantDriftTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "mysql_user_grant_drift_total",
+			Help:      "Number of detected drifts of MySQL User grants",
+		},
+	)
+
+	mysqlUserGrantStatementsSavedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "mysql_user_grant_statements_saved_total",
+			Help:      "Number of SHOW GRANTS statements saved by the grant cache",
+		},
+	)
+
+	MysqlUserCreatedTotal              *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: userCreatedTotal}
+	MysqlUserDeletedTotal              *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: mysqlUserDeletedTotal}
+	MysqlUserGrantDriftTotal           *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: mysqlUserGrantDriftTotal}
+	MysqlUserGrantStatementsSavedTotal *MysqlUserTotalAdaptor = &MysqlUserTotalAdaptor{metric: mysqlUserGrantStatementsSavedTotal}
 )
 
 func init() {
@@ -41,5 +59,7 @@ func init() {
 	metrics.Registry.MustRegister(
 		userCreatedTotal,
 		mysqlUserDeletedTotal,
+		mysqlUserGrantDriftTotal,
+		mysqlUserGrantStatementsSavedTotal,
 	)
 }
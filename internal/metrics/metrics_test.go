@@ -26,6 +26,26 @@ func TestMySQLUserDeletedMetrics(t *testing.T) {
 	assertFloat64(t, float64(2), actual)
 }
 
+func TestMySQLUserGrantDriftMetrics(t *testing.T) {
+	MysqlUserGrantDriftTotal.Increment()
+	actual := testutil.ToFloat64(mysqlUserGrantDriftTotal)
+	assertFloat64(t, float64(1), actual)
+
+	MysqlUserGrantDriftTotal.Increment()
+	actual = testutil.ToFloat64(mysqlUserGrantDriftTotal)
+	assertFloat64(t, float64(2), actual)
+}
+
+func TestMySQLUserGrantStatementsSavedMetrics(t *testing.T) {
+	MysqlUserGrantStatementsSavedTotal.Increment()
+	actual := testutil.ToFloat64(mysqlUserGrantStatementsSavedTotal)
+	assertFloat64(t, float64(1), actual)
+
+	MysqlUserGrantStatementsSavedTotal.Increment()
+	actual = testutil.ToFloat64(mysqlUserGrantStatementsSavedTotal)
+	assertFloat64(t, float64(2), actual)
+}
+
 func assertFloat64(t *testing.T, expected, actual float64) {
 	if actual != expected {
 		t.Errorf("value is not %f", expected)
@@ -0,0 +1,180 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// BucketSourceName is the name of the bucket source for migrate.NewWithInstance
+const BucketSourceName = "bucket"
+
+// DefaultBucketEndpoint is the endpoint of Amazon S3
+const DefaultBucketEndpoint = "https://s3.amazonaws.com"
+
+// Keys of the credentials of a bucket in a Secret
+const (
+	BucketAccessKeyIDKey     = "accessKeyID"
+	BucketSecretAccessKeyKey = "secretAccessKey"
+	BucketSessionTokenKey    = "sessionToken"
+)
+
+// Bucket is a bucket of an S3 compatible object storage with migration files,
+// e.g. Amazon S3, MinIO, or Google Cloud Storage with HMAC keys
+type Bucket struct {
+	// URL of the endpoint, e.g. http://minio.minio:9000. Default to DefaultBucketEndpoint.
+	Endpoint string
+	// Region of the bucket, which is looked up if not set
+	Region string
+	// Name of the bucket
+	Name string
+	// Directory of the migration files in the bucket, e.g. sales/v1.2.0. Default to the root.
+	Prefix string
+	// Data of the Secret with the credentials: accessKeyID, secretAccessKey and
+	// optionally sessionToken
+	Credentials map[string][]byte
+	// AmbientCredentials allows the credentials of the operator, read from the
+	// environment variables, the AWS credentials file or IAM, without Credentials
+	AmbientCredentials bool
+}
+
+// NewBucketSource downloads the migration files directly under the prefix of
+// the bucket and returns a MemorySource with them.
+func NewBucketSource(ctx context.Context, bucket Bucket) (*MemorySource, error) {
+	client, err := bucket.client()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := bucket.listObjects(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newMemorySource(BucketSourceName)
+	origin := fmt.Sprintf("bucket %s", bucket.Name)
+	for _, object := range objects {
+		data, err := getObject(ctx, client, bucket.Name, object.Key)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(origin, path.Base(object.Key), data); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// BucketRevision returns a digest of the keys and ETags of the objects
+// directly under the prefix of the bucket, which changes with any of them.
+func BucketRevision(ctx context.Context, bucket Bucket) (string, error) {
+	client, err := bucket.client()
+	if err != nil {
+		return "", err
+	}
+	objects, err := bucket.listObjects(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, object := range objects {
+		fmt.Fprintf(h, "%s %s\n", object.Key, object.ETag)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// listObjects returns the objects directly under the prefix in the order of the keys
+func (b Bucket) listObjects(ctx context.Context, client *minio.Client) ([]minio.ObjectInfo, error) {
+	prefix := strings.Trim(b.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	var objects []minio.ObjectInfo
+	for object := range client.ListObjects(ctx, b.Name, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list %s in bucket %s: %w", prefix, b.Name, object.Err)
+		}
+		// Skip the directories under the prefix
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func getObject(ctx context.Context, client *minio.Client, bucketName, key string) ([]byte, error) {
+	object, err := client.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s in bucket %s: %w", key, bucketName, err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in bucket %s: %w", key, bucketName, err)
+	}
+	return data, nil
+}
+
+// client returns the client for the endpoint with the credentials
+func (b Bucket) client() (*minio.Client, error) {
+	endpoint := b.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultBucketEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: must be a URL such as http://minio:9000", endpoint)
+	}
+
+	var creds *credentials.Credentials
+	if len(b.Credentials) == 0 {
+		if !b.AmbientCredentials {
+			return nil, fmt.Errorf("no credentials for bucket %s: the credentials of the operator are not allowed", b.Name)
+		}
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		})
+	} else {
+		if len(b.Credentials[BucketAccessKeyIDKey]) == 0 || len(b.Credentials[BucketSecretAccessKeyKey]) == 0 {
+			return nil, fmt.Errorf("%s and %s are required in the Secret for bucket %s", BucketAccessKeyIDKey, BucketSecretAccessKeyKey, b.Name)
+		}
+		creds = credentials.NewStaticV4(
+			string(b.Credentials[BucketAccessKeyIDKey]),
+			string(b.Credentials[BucketSecretAccessKeyKey]),
+			string(b.Credentials[BucketSessionTokenKey]),
+		)
+	}
+	return minio.New(u.Host, &minio.Options{
+		Creds:  creds,
+		Secure: u.Scheme != "http",
+		Region: b.Region,
+	})
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+	"github.com/minio/minio-go/v7"
+)
+
+// bucketForTest returns a Bucket with the objects. The objects are put to the
+// MinIO at MINIO_ENDPOINT if it is set, e.g. with
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	MINIO_ENDPOINT=http://localhost:9000 MINIO_ACCESS_KEY=minioadmin MINIO_SECRET_KEY=minioadmin go test ./internal/migration
+//
+// and served by a fake S3 server otherwise.
+func bucketForTest(t *testing.T, name string, objects map[string]string) Bucket {
+	t.Helper()
+	bucket := Bucket{Region: "us-east-1", Name: name}
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		server := httptest.NewServer(fakeS3(name, objects))
+		t.Cleanup(server.Close)
+		bucket.Endpoint = server.URL
+		bucket.Credentials = map[string][]byte{BucketAccessKeyIDKey: []byte("id"), BucketSecretAccessKeyKey: []byte("secret")}
+		return bucket
+	}
+
+	bucket.Endpoint = endpoint
+	bucket.Credentials = map[string][]byte{
+		BucketAccessKeyIDKey:     []byte(os.Getenv("MINIO_ACCESS_KEY")),
+		BucketSecretAccessKeyKey: []byte(os.Getenv("MINIO_SECRET_KEY")),
+	}
+	client, err := bucket.client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if exists, err := client.BucketExists(ctx, name); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		if err := client.MakeBucket(ctx, name, minio.MakeBucketOptions{Region: bucket.Region}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, data := range objects {
+		if _, err := client.PutObject(ctx, name, key, bytes.NewReader([]byte(data)), int64(len(data)), minio.PutObjectOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = client.RemoveObject(ctx, name, key, minio.RemoveObjectOptions{}) })
+	}
+	return bucket
+}
+
+// fakeS3 serves ListObjectsV2 and GetObject of the objects in the bucket
+func fakeS3(name string, objects map[string]string) http.Handler {
+	type content struct {
+		Key  string
+		Size int
+		ETag string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	type listBucketResult struct {
+		XMLName        xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name           string
+		Prefix         string
+		Delimiter      string
+		KeyCount       int
+		MaxKeys        int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"+name), "/")
+		if key != "" {
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Last-Modified", "Mon, 2 Jan 2006 15:04:05 GMT")
+			w.Header().Set("ETag", `"etag"`)
+			_, _ = w.Write([]byte(data))
+			return
+		}
+
+		query := r.URL.Query()
+		result := listBucketResult{Name: name, Prefix: query.Get("prefix"), Delimiter: query.Get("delimiter"), MaxKeys: 1000}
+		prefixes := map[string]bool{}
+		for key, data := range objects {
+			rest, ok := strings.CutPrefix(key, result.Prefix)
+			if !ok {
+				continue
+			}
+			if i := strings.Index(rest, result.Delimiter); result.Delimiter != "" && i >= 0 {
+				prefixes[result.Prefix+rest[:i+1]] = true
+				continue
+			}
+			result.Contents = append(result.Contents, content{Key: key, Size: len(data), ETag: fmt.Sprintf(`"%x"`, md5.Sum([]byte(data)))})
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		for prefix := range prefixes {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: prefix})
+		}
+		result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(result)
+	})
+}
+
+func TestBucketSource(t *testing.T) {
+	bucket := bucketForTest(t, "migrations", map[string]string{
+		"sales/v1/1_foobar.up.sql":     "1 up",
+		"sales/v1/1_foobar.down.sql":   "1 down",
+		"sales/v1/3_foobar.up.sql":     "3 up",
+		"sales/v1/4_foobar.up.sql":     "4 up",
+		"sales/v1/4_foobar.down.sql":   "4 down",
+		"sales/v1/5_foobar.down.sql":   "5 down",
+		"sales/v1/7_foobar.up.sql":     "7 up",
+		"sales/v1/7_foobar.down.sql":   "7 down",
+		"sales/v1/README.md":           "not a migration",
+		"sales/v1/old/8_foobar.up.sql": "not directly under the prefix",
+		"sales/v2/9_foobar.up.sql":     "another version",
+	})
+	bucket.Prefix = "/sales/v1/"
+	s, err := NewBucketSource(context.Background(), bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, s)
+}
+
+func TestBucketRevision(t *testing.T) {
+	objects := map[string]string{
+		"sales/v1/1_create.up.sql":    "v1",
+		"sales/v1/old/2_alter.up.sql": "not directly under the prefix",
+		"sales/v2/1_create.up.sql":    "another version",
+	}
+	// The objects are changed in the fake S3 server
+	server := httptest.NewServer(fakeS3("revisions", objects))
+	defer server.Close()
+	bucket := Bucket{
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Name:        "revisions",
+		Prefix:      "sales/v1",
+		Credentials: map[string][]byte{BucketAccessKeyIDKey: []byte("id"), BucketSecretAccessKeyKey: []byte("secret")},
+	}
+	revision, err := BucketRevision(context.Background(), bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the objects directly under the prefix change the revision
+	objects["sales/v1/old/2_alter.up.sql"] = "changed"
+	objects["sales/v2/1_create.up.sql"] = "changed"
+	if same, err := BucketRevision(context.Background(), bucket); err != nil || same != revision {
+		t.Errorf("revision = %s, %v, want %s", same, err, revision)
+	}
+	objects["sales/v1/1_create.up.sql"] = "v2"
+	if changed, err := BucketRevision(context.Background(), bucket); err != nil || changed == revision {
+		t.Errorf("revision = %s, %v, want another revision than %s", changed, err, revision)
+	}
+}
+
+func TestBucketClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		bucket  Bucket
+		wantErr bool
+	}{
+		{name: "default endpoint", bucket: Bucket{Name: "migrations", AmbientCredentials: true}},
+		{name: "endpoint override", bucket: Bucket{Endpoint: "http://minio.minio:9000", Name: "migrations", AmbientCredentials: true}},
+		{name: "endpoint without scheme", bucket: Bucket{Endpoint: "minio.minio:9000", Name: "migrations", AmbientCredentials: true}, wantErr: true},
+		{name: "no credentials", bucket: Bucket{Name: "migrations"}, wantErr: true},
+		{name: "secret without secretAccessKey", bucket: Bucket{Name: "migrations", Credentials: map[string][]byte{BucketAccessKeyIDKey: []byte("id")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.bucket.client()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("client() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConfigMapSourceName is the name of the ConfigMap source for migrate.NewWithInstance
+const ConfigMapSourceName = "configmap"
+
+// NewConfigMapSource returns a MemorySource with the migration files in the
+// data of the ConfigMaps. Each key is a file name such as 1_create_table.up.sql.
+// A file name or a version can't be in more than one ConfigMap.
+func NewConfigMapSource(configMaps []corev1.ConfigMap) (*MemorySource, error) {
+	s := newMemorySource(ConfigMapSourceName)
+	for _, configMap := range configMaps {
+		for name, data := range configMap.Data {
+			if err := s.add("ConfigMap "+configMap.Name, name, []byte(data)); err != nil {
+				return nil, err
+			}
+		}
+		for name, data := range configMap.BinaryData {
+			if err := s.add("ConfigMap "+configMap.Name, name, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
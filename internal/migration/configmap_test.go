@@ -0,0 +1,76 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"io"
+	"testing"
+
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigMapSource(t *testing.T) {
+	configMaps := []corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrations-1"},
+			Data: map[string]string{
+				"1_foobar.up.sql":   "1 up",
+				"1_foobar.down.sql": "1 down",
+				"3_foobar.up.sql":   "3 up",
+				"README.md":         "not a migration",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrations-2"},
+			Data: map[string]string{
+				"4_foobar.up.sql":   "4 up",
+				"4_foobar.down.sql": "4 down",
+				"5_foobar.down.sql": "5 down",
+				"7_foobar.up.sql":   "7 up",
+			},
+			BinaryData: map[string][]byte{
+				"7_foobar.down.sql": []byte("7 down"),
+			},
+		},
+	}
+	s, err := NewConfigMapSource(configMaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, s)
+
+	r, identifier, err := s.ReadUp(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	if string(body) != "3 up" || identifier != "foobar" {
+		t.Errorf("ReadUp(3) = %q, %q", body, identifier)
+	}
+}
+
+func TestConfigMapSourceDuplicatedVersion(t *testing.T) {
+	configMaps := []corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Data: map[string]string{"1_create.up.sql": ""}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b"}, Data: map[string]string{"1_other.up.sql": ""}},
+	}
+	if _, err := NewConfigMapSource(configMaps); err == nil {
+		t.Error("expected an error for the duplicated version")
+	}
+}
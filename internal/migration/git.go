@@ -0,0 +1,316 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+)
+
+// GitSourceName is the name of the git source for migrate.NewWithInstance
+const GitSourceName = "git"
+
+// Keys of the credentials of a git repository in a Secret
+const (
+	GitUsernameKey   = "username"
+	GitPasswordKey   = "password"
+	GitIdentityKey   = "identity"
+	GitKnownHostsKey = "known_hosts"
+)
+
+// GitRepository is a git repository with migration files
+type GitRepository struct {
+	// URL over HTTPS or SSH, e.g. https://gitlab.com/org/repo.git or
+	// git@gitea.example.com:org/repo.git, or the path of a local repository
+	URL string
+	// Branch, tag or full commit SHA. Default to HEAD.
+	Ref string
+	// Directory of the migration files. Default to the root.
+	Path string
+	// Data of the Secret with the credentials: username and password (or a
+	// token) for HTTPS, and identity (a private key) and known_hosts for SSH
+	Credentials map[string][]byte
+}
+
+// NewGitSource clones the repository into memory and returns a MemorySource
+// with the migration files in the path at the ref, and the commit SHA of the ref.
+func NewGitSource(ctx context.Context, repository GitRepository) (*MemorySource, string, error) {
+	auth, err := repository.auth()
+	if err != nil {
+		return nil, "", err
+	}
+	repo, hash, err := repository.clone(ctx, auth)
+	if err != nil {
+		return nil, "", err
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, "", err
+	}
+	if path := strings.Trim(repository.Path, "/"); path != "" && path != "." {
+		if tree, err = tree.Tree(path); err != nil {
+			return nil, "", fmt.Errorf("failed to read %s at %s: %w", path, hash, err)
+		}
+	}
+
+	s := newMemorySource(GitSourceName)
+	origin := fmt.Sprintf("%s at %s", repository.URL, hash)
+	for _, entry := range tree.Entries {
+		if !entry.Mode.IsFile() {
+			continue
+		}
+		data, err := readFile(tree, entry)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := s.add(origin, entry.Name, data); err != nil {
+			return nil, "", err
+		}
+	}
+	return s, hash.String(), nil
+}
+
+// ResolveGitRef returns the commit SHA of the ref of the repository, listing
+// the refs of the remote without cloning it. A full commit SHA is returned as is.
+func ResolveGitRef(ctx context.Context, repository GitRepository) (string, error) {
+	if plumbing.IsHash(repository.Ref) {
+		return repository.Ref, nil
+	}
+	auth, err := repository.auth()
+	if err != nil {
+		return "", err
+	}
+	refs, err := repository.remoteRefs(ctx, auth)
+	if err != nil {
+		return "", err
+	}
+	name, err := repository.refName(refs)
+	if err != nil {
+		return "", err
+	}
+	return refs[name].String(), nil
+}
+
+// clone fetches the commit of the ref into memory without its history, and
+// returns the repository and the commit SHA. A commit SHA that is not at the
+// tip of a branch or tag is fetched by itself if the server allows it, and
+// with the history of all the branches and tags otherwise.
+func (r GitRepository) clone(ctx context.Context, auth transport.AuthMethod) (*git.Repository, plumbing.Hash, error) {
+	refs, err := r.remoteRefs(ctx, auth)
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	var name plumbing.ReferenceName
+	if plumbing.IsHash(r.Ref) {
+		name = tipOf(refs, plumbing.NewHash(r.Ref))
+	} else if name, err = r.refName(refs); err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+
+	if name != "" {
+		repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+			URL:           r.URL,
+			Auth:          auth,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         1,
+			Tags:          git.NoTags,
+		})
+		if err != nil {
+			return nil, plumbing.ZeroHash, fmt.Errorf("failed to clone %s of %s: %w", name, r.URL, err)
+		}
+		// The ref might have moved since it was listed
+		hash, err := repo.ResolveRevision(plumbing.Revision(name))
+		if err != nil {
+			return nil, plumbing.ZeroHash, fmt.Errorf("failed to resolve ref %s of %s: %w", name, r.URL, err)
+		}
+		return repo, *hash, nil
+	}
+
+	hash := plumbing.NewHash(r.Ref)
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	remote, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{r.URL}})
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		Auth:     auth,
+		RefSpecs: []config.RefSpec{config.RefSpec(hash.String() + ":refs/heads/migrations")},
+		Depth:    1,
+		Tags:     git.NoTags,
+	})
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		err = remote.FetchContext(ctx, &git.FetchOptions{
+			Auth:     auth,
+			RefSpecs: []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+			Tags:     git.NoTags,
+		})
+	}
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to fetch %s of %s: %w", hash, r.URL, err)
+	}
+	return repo, hash, nil
+}
+
+// remoteRefs lists the refs of the remote with the SHAs of their commits:
+// symbolic refs such as HEAD are resolved and annotated tags are peeled.
+func (r GitRepository) remoteRefs(ctx context.Context, auth transport.AuthMethod) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{r.URL}})
+	list, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs of %s: %w", r.URL, err)
+	}
+	byName := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, ref := range list {
+		byName[ref.Name()] = ref
+	}
+	refs := map[plumbing.ReferenceName]plumbing.Hash{}
+	for name, ref := range byName {
+		if strings.HasSuffix(name.String(), "^{}") {
+			continue
+		}
+		if ref.Type() == plumbing.SymbolicReference {
+			if ref = byName[ref.Target()]; ref == nil {
+				continue
+			}
+		}
+		if peeled, ok := byName[ref.Name()+"^{}"]; ok {
+			ref = peeled
+		}
+		refs[name] = ref.Hash()
+	}
+	return refs, nil
+}
+
+// refName returns the full name of the ref in the refs of the remote.
+// Tags take precedence over branches with the same name as in git.
+func (r GitRepository) refName(refs map[plumbing.ReferenceName]plumbing.Hash) (plumbing.ReferenceName, error) {
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	if r.Ref != "" && r.Ref != string(plumbing.HEAD) {
+		names = []plumbing.ReferenceName{plumbing.ReferenceName(r.Ref), plumbing.NewTagReferenceName(r.Ref), plumbing.NewBranchReferenceName(r.Ref)}
+	}
+	for _, name := range names {
+		if _, ok := refs[name]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("ref %s is not found in %s", names[0], r.URL)
+}
+
+// tipOf returns a branch or tag at the commit, or an empty name if there is none
+func tipOf(refs map[plumbing.ReferenceName]plumbing.Hash, hash plumbing.Hash) plumbing.ReferenceName {
+	names := make([]plumbing.ReferenceName, 0, len(refs))
+	for name := range refs {
+		if (name.IsBranch() || name.IsTag()) && refs[name] == hash {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	slices.Sort(names)
+	return names[0]
+}
+
+func readFile(tree *object.Tree, entry object.TreeEntry) ([]byte, error) {
+	file, err := tree.TreeEntryFile(&entry)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+// auth returns the auth method for the protocol of the URL with the credentials.
+// Without credentials, the default of go-git is used, e.g. ssh-agent for SSH.
+func (r GitRepository) auth() (transport.AuthMethod, error) {
+	if len(r.Credentials) == 0 {
+		return nil, nil
+	}
+	endpoint, err := transport.NewEndpoint(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch endpoint.Protocol {
+	case "http", "https":
+		username := string(r.Credentials[GitUsernameKey])
+		if username == "" {
+			// Hosts such as GitLab accept a token with any username
+			username = "git"
+		}
+		return &githttp.BasicAuth{Username: username, Password: string(r.Credentials[GitPasswordKey])}, nil
+	case "ssh":
+		if len(r.Credentials[GitIdentityKey]) == 0 {
+			return nil, fmt.Errorf("%s is required in the Secret to clone %s over SSH", GitIdentityKey, r.URL)
+		}
+		if len(r.Credentials[GitKnownHostsKey]) == 0 {
+			return nil, fmt.Errorf("%s is required in the Secret to clone %s over SSH", GitKnownHostsKey, r.URL)
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		keys, err := gitssh.NewPublicKeys(user, r.Credentials[GitIdentityKey], string(r.Credentials[GitPasswordKey]))
+		if err != nil {
+			return nil, err
+		}
+		if keys.HostKeyCallback, err = knownHostsCallback(r.Credentials[GitKnownHostsKey]); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// knownHostsCallback returns the callback that accepts the host keys in
+// knownHosts, which go-git only reads from files.
+func knownHostsCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.Write(knownHosts); err != nil {
+		return nil, err
+	}
+	return gitssh.NewKnownHostsCallback(file.Name())
+}
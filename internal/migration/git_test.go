@@ -0,0 +1,241 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+)
+
+// newGitRepository creates a repository in a temporary directory with a
+// commit on main for each set of files and returns the directory and the commits
+func newGitRepository(t *testing.T, commits ...map[string]string) (string, []plumbing.Hash) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	hashes := []plumbing.Hash{}
+	for _, files := range commits {
+		for name, data := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := worktree.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := worktree.Commit("add migrations", &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return dir, hashes
+}
+
+func TestGitSource(t *testing.T) {
+	dir, hashes := newGitRepository(t,
+		map[string]string{
+			"migrations/1_foobar.up.sql":   "1 up",
+			"migrations/1_foobar.down.sql": "1 down",
+			"migrations/3_foobar.up.sql":   "3 up",
+			"migrations/4_foobar.up.sql":   "4 up",
+			"migrations/4_foobar.down.sql": "4 down",
+			"migrations/5_foobar.down.sql": "5 down",
+			"migrations/7_foobar.up.sql":   "7 up",
+			"migrations/7_foobar.down.sql": "7 down",
+			"migrations/README.md":         "not a migration",
+			"8_outside.up.sql":             "not in the path",
+		},
+	)
+	s, revision, err := NewGitSource(context.Background(), GitRepository{URL: dir, Path: "migrations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != hashes[0].String() {
+		t.Errorf("revision = %s, want %s", revision, hashes[0])
+	}
+	st.Test(t, s)
+}
+
+func TestGitSourceRef(t *testing.T) {
+	dir, hashes := newGitRepository(t,
+		map[string]string{"1_create.up.sql": "v1"},
+		map[string]string{"1_create.up.sql": "v2"},
+	)
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0", hashes[0], &git.CreateTagOptions{Message: "v1.0.0", Tagger: &object.Signature{Name: "test", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("release"), hashes[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref      string
+		revision plumbing.Hash
+		data     string
+	}{
+		{ref: "", revision: hashes[1], data: "v2"},
+		{ref: "main", revision: hashes[1], data: "v2"},
+		{ref: "release", revision: hashes[0], data: "v1"},
+		{ref: "v1.0.0", revision: hashes[0], data: "v1"},
+		{ref: hashes[0].String(), revision: hashes[0], data: "v1"},
+	}
+	for _, tt := range tests {
+		s, revision, err := NewGitSource(context.Background(), GitRepository{URL: dir, Ref: tt.ref})
+		if err != nil {
+			t.Fatalf("ref %q: %v", tt.ref, err)
+		}
+		if revision != tt.revision.String() {
+			t.Errorf("ref %q: revision = %s, want %s", tt.ref, revision, tt.revision)
+		}
+		r, _, err := s.ReadUp(1)
+		if err != nil {
+			t.Fatalf("ref %q: %v", tt.ref, err)
+		}
+		body, _ := io.ReadAll(r)
+		if string(body) != tt.data {
+			t.Errorf("ref %q: ReadUp(1) = %q, want %q", tt.ref, body, tt.data)
+		}
+	}
+
+	if _, _, err := NewGitSource(context.Background(), GitRepository{URL: dir, Ref: "unknown"}); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
+
+func TestGitRepositoryClone(t *testing.T) {
+	dir, hashes := newGitRepository(t,
+		map[string]string{"1_create.up.sql": "v1"},
+		map[string]string{"1_create.up.sql": "v2"},
+		map[string]string{"1_create.up.sql": "v3"},
+	)
+	repository := GitRepository{URL: dir, Ref: "main"}
+
+	// Only the commit at the tip of the ref is fetched
+	repo, hash, err := repository.clone(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != hashes[2] {
+		t.Errorf("hash = %s, want %s", hash, hashes[2])
+	}
+	if _, err := repo.CommitObject(hashes[1]); err == nil {
+		t.Error("expected the history not to be fetched")
+	}
+
+	// A commit in the middle of the history is fetched too
+	repository.Ref = hashes[1].String()
+	repo, hash, err = repository.clone(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != hashes[1] {
+		t.Errorf("hash = %s, want %s", hash, hashes[1])
+	}
+	if _, err := repo.CommitObject(hash); err != nil {
+		t.Errorf("commit %s is not fetched: %v", hash, err)
+	}
+}
+
+func TestResolveGitRef(t *testing.T) {
+	dir, hashes := newGitRepository(t,
+		map[string]string{"1_create.up.sql": "v1"},
+		map[string]string{"1_create.up.sql": "v2"},
+	)
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0", hashes[0], &git.CreateTagOptions{Message: "v1.0.0", Tagger: &object.Signature{Name: "test", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("release"), hashes[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref      string
+		revision plumbing.Hash
+	}{
+		{ref: "", revision: hashes[1]},
+		{ref: "main", revision: hashes[1]},
+		{ref: "refs/heads/release", revision: hashes[0]},
+		{ref: "v1.0.0", revision: hashes[0]},
+		{ref: hashes[0].String(), revision: hashes[0]},
+	}
+	for _, tt := range tests {
+		revision, err := ResolveGitRef(context.Background(), GitRepository{URL: dir, Ref: tt.ref})
+		if err != nil {
+			t.Fatalf("ref %q: %v", tt.ref, err)
+		}
+		if revision != tt.revision.String() {
+			t.Errorf("ref %q: revision = %s, want %s", tt.ref, revision, tt.revision)
+		}
+	}
+
+	if _, err := ResolveGitRef(context.Background(), GitRepository{URL: dir, Ref: "unknown"}); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
+
+func TestGitRepositoryAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    GitRepository
+		wantErr bool
+	}{
+		{name: "no credentials", repo: GitRepository{URL: "git@gitea.example.com:org/repo.git"}},
+		{name: "https", repo: GitRepository{URL: "https://gitlab.com/org/repo.git", Credentials: map[string][]byte{GitPasswordKey: []byte("token")}}},
+		{name: "ssh without identity", repo: GitRepository{URL: "ssh://git@bitbucket.org/org/repo.git", Credentials: map[string][]byte{GitKnownHostsKey: []byte("bitbucket.org ssh-ed25519 AAAA")}}, wantErr: true},
+		{name: "ssh without known_hosts", repo: GitRepository{URL: "git@gitea.example.com:org/repo.git", Credentials: map[string][]byte{GitIdentityKey: []byte("key")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.repo.auth()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("auth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
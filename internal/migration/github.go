@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHubAPIURL is the URL of the GitHub API to create the tokens of GitHub Apps
+var GitHubAPIURL = "https://api.github.com"
+
+// gitHubClient is the client of the GitHub API, which gives up on a slow
+// response rather than blocking the reconciliation
+var gitHubClient = &http.Client{Timeout: 30 * time.Second}
+
+// GitHubAppToken returns an installation access token of the GitHub App,
+// which is valid for an hour, signing a JWT with the private key of the app.
+// Ref: https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/authenticating-as-a-github-app-installation
+func GitHubAppToken(ctx context.Context, appID, installationID int64, privateKey []byte) (string, error) {
+	jwt, err := gitHubAppJWT(appID, privateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("%s/app/installations/%d/access_tokens", strings.TrimSuffix(GitHubAPIURL, "/"), installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := gitHubClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to create a token of GitHub App %d for installation %d: %s", appID, installationID, res.Status)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token == "" {
+		return "", fmt.Errorf("no token of GitHub App %d for installation %d", appID, installationID)
+	}
+	return body.Token, nil
+}
+
+// GitHubRevision returns the SHA of the last commit that changed the path at
+// the ref of the repository, so that only changes of the migration files give
+// another revision. The token is only needed for private repositories.
+// Ref: https://docs.github.com/en/rest/commits/commits#list-commits
+func GitHubRevision(ctx context.Context, owner, repo, path, ref, token string) (string, error) {
+	query := url.Values{"path": {path}, "per_page": {"1"}}
+	if ref != "" {
+		query.Set("sha", ref)
+	}
+	u := fmt.Sprintf("%s/repos/%s/%s/commits?%s", strings.TrimSuffix(GitHubAPIURL, "/"), owner, repo, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := gitHubClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to list commits of %s/%s/%s at %q: %s", owner, repo, path, ref, res.Status)
+	}
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commit of %s/%s/%s at %q", owner, repo, path, ref)
+	}
+	return commits[0].SHA, nil
+}
+
+// gitHubAppJWT returns a JWT signed with RS256 to authenticate as the GitHub App
+func gitHubAppJWT(appID int64, privateKey []byte, now time.Time) (string, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return "", errors.New("private key of GitHub App is not in PEM format")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", errors.New("private key of GitHub App is not an RSA key")
+		}
+		key = rsaKey
+	} else {
+		return "", fmt.Errorf("failed to parse private key of GitHub App: %w", err)
+	}
+
+	encode := func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b), err
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// Issued a minute ago against clock drift, and expires within the maximum of 10 minutes
+	claims, err := encode(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + claims
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// RedactToken returns err with the token masked, for errors that may contain
+// the source URL with the token
+func RedactToken(err error, token string) error {
+	if err == nil || token == "" || !strings.Contains(err.Error(), token) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), token, "***"))
+}
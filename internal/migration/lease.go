@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultLeaseDuration is how long a Lease is held without being renewed
+const DefaultLeaseDuration = 30 * time.Second
+
+// LeaseLocker is a Locker with a Kubernetes Lease, which is shared by the
+// operator and the migration Jobs. The Lease is renewed while it is held,
+// and taken over by another holder once it expires, e.g. after a crash.
+type LeaseLocker struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+
+	// Holder of the Lease, unique to each LeaseLocker
+	Identity string
+
+	// The Lease expires after Duration without being renewed
+	Duration time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ Locker = &LeaseLocker{}
+
+// NewLeaseLocker returns a LeaseLocker with the Lease of the name, held by
+// the host name with a random suffix
+func NewLeaseLocker(c client.Client, namespace, name string) *LeaseLocker {
+	hostname, _ := os.Hostname()
+	return &LeaseLocker{
+		Client:    c,
+		Namespace: namespace,
+		Name:      name,
+		Identity:  fmt.Sprintf("%s_%s", hostname, uuid.NewUUID()),
+		Duration:  DefaultLeaseDuration,
+	}
+}
+
+// Lock creates the Lease, or takes it over if it is not held or expired.
+// It doesn't wait for the Lease held by another.
+func (l *LeaseLocker) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop != nil {
+		return fmt.Errorf("%w: lease %s/%s is already held", database.ErrLocked, l.Namespace, l.Name)
+	}
+
+	now := time.Now()
+	lease := &coordinationv1.Lease{}
+	err := l.Client.Get(ctx, client.ObjectKey{Namespace: l.Namespace, Name: l.Name}, lease)
+	switch {
+	case apierrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.Namespace, Name: l.Name},
+			Spec:       l.spec(now),
+		}
+		err = l.Client.Create(ctx, lease)
+	case err != nil:
+		return err
+	default:
+		if holder := heldBy(lease, now); holder != "" && holder != l.Identity {
+			return fmt.Errorf("%w: lease %s/%s is held by %s", database.ErrLocked, l.Namespace, l.Name, holder)
+		}
+		lease.Spec = l.spec(now)
+		err = l.Client.Update(ctx, lease)
+	}
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		return fmt.Errorf("%w: lease %s/%s is taken by another", database.ErrLocked, l.Namespace, l.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+	go l.renew(l.stop, l.done)
+	return nil
+}
+
+// Unlock stops renewing the Lease and deletes it unless it is taken over
+func (l *LeaseLocker) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop == nil {
+		return database.ErrNotLocked
+	}
+	close(l.stop)
+	<-l.done
+	l.stop, l.done = nil, nil
+
+	lease := &coordinationv1.Lease{}
+	if err := l.Client.Get(ctx, client.ObjectKey{Namespace: l.Namespace, Name: l.Name}, lease); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Identity {
+		return nil
+	}
+	err := l.Client.Delete(ctx, lease, client.Preconditions{UID: &lease.UID, ResourceVersion: &lease.ResourceVersion})
+	if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// renew renews the Lease every third of the duration until stop is closed
+func (l *LeaseLocker) renew(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	log := log.Log.WithName("LeaseLocker").WithValues("lease", l.Namespace+"/"+l.Name)
+	ticker := time.NewTicker(l.Duration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.Duration/3)
+			lease := &coordinationv1.Lease{}
+			err := l.Client.Get(ctx, client.ObjectKey{Namespace: l.Namespace, Name: l.Name}, lease)
+			if err == nil {
+				if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Identity {
+					log.Info("lease is taken over", "holder", ptr.Deref(lease.Spec.HolderIdentity, ""))
+					cancel()
+					continue
+				}
+				lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+				err = l.Client.Update(ctx, lease)
+			}
+			cancel()
+			if err != nil {
+				log.Error(err, "failed to renew lease")
+			}
+		}
+	}
+}
+
+func (l *LeaseLocker) spec(now time.Time) coordinationv1.LeaseSpec {
+	return coordinationv1.LeaseSpec{
+		HolderIdentity:       ptr.To(l.Identity),
+		LeaseDurationSeconds: ptr.To(int32(l.Duration.Seconds())),
+		AcquireTime:          &metav1.MicroTime{Time: now},
+		RenewTime:            &metav1.MicroTime{Time: now},
+	}
+}
+
+// heldBy returns the holder of the Lease, or an empty string if it is not held or expired
+func heldBy(lease *coordinationv1.Lease, now time.Time) string {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return ""
+	}
+	if spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second).Before(now) {
+		return ""
+	}
+	return *spec.HolderIdentity
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLeaseLocker(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+	key := client.ObjectKey{Namespace: "default", Name: "sales"}
+	operator := NewLeaseLocker(c, key.Namespace, key.Name)
+	job := NewLeaseLocker(c, key.Namespace, key.Name)
+
+	if err := operator.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	lease := &coordinationv1.Lease{}
+	if err := c.Get(ctx, key, lease); err != nil {
+		t.Fatal(err)
+	}
+	if got := ptr.Deref(lease.Spec.HolderIdentity, ""); got != operator.Identity {
+		t.Errorf("holder = %s, want %s", got, operator.Identity)
+	}
+	if err := job.Lock(ctx); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("Lock() error = %v, want ErrLocked", err)
+	}
+
+	if err := operator.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, lease); !apierrors.IsNotFound(err) {
+		t.Errorf("lease is not deleted: %v", err)
+	}
+	if err := operator.Unlock(ctx); !errors.Is(err, database.ErrNotLocked) {
+		t.Errorf("Unlock() error = %v, want ErrNotLocked", err)
+	}
+
+	if err := job.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaseLockerTakesOverExpiredLease(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	expired := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sales"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("crashed"),
+			LeaseDurationSeconds: ptr.To(int32(30)),
+			RenewTime:            &metav1.MicroTime{Time: time.Now().Add(-time.Minute)},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(expired).Build()
+	ctx := context.Background()
+	locker := NewLeaseLocker(c, "default", "sales")
+	locker.Duration = 300 * time.Millisecond
+
+	if err := locker.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	lease := &coordinationv1.Lease{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(expired), lease); err != nil {
+		t.Fatal(err)
+	}
+	acquired := lease.Spec.RenewTime.Time
+
+	// The Lease is renewed while it is held
+	time.Sleep(250 * time.Millisecond)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(expired), lease); err != nil {
+		t.Fatal(err)
+	}
+	if !lease.Spec.RenewTime.After(acquired) {
+		t.Errorf("lease is not renewed: %v", lease.Spec.RenewTime)
+	}
+
+	// The Lease taken over by another is left as it is
+	lease.Spec.HolderIdentity = ptr.To("another")
+	if err := c.Update(ctx, lease); err != nil {
+		t.Fatal(err)
+	}
+	if err := locker.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(expired), lease); err != nil {
+		t.Fatal(err)
+	}
+}
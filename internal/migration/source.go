@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration has the golang-migrate source drivers for the schema
+// migrations of MySQLDB besides GitHub, the credentials for GitHub, and the
+// database driver for StarRocks and Doris with its lock.
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// MemorySource is a golang-migrate source driver that serves migration
+// files read into memory, e.g. from ConfigMaps or a git repository.
+// Files whose names are not migration files such as 1_create_table.up.sql are ignored.
+type MemorySource struct {
+	name       string
+	migrations *source.Migrations
+	files      map[string][]byte
+}
+
+var _ source.Driver = &MemorySource{}
+
+func newMemorySource(name string) *MemorySource {
+	return &MemorySource{name: name, migrations: source.NewMigrations(), files: map[string][]byte{}}
+}
+
+// add adds the migration file read from the origin, e.g. ConfigMap foo.
+// A file name or a version can't be added more than once.
+func (s *MemorySource) add(origin, name string, data []byte) error {
+	m, err := source.DefaultParse(name)
+	if err != nil {
+		return nil
+	}
+	if _, ok := s.files[name]; ok {
+		return fmt.Errorf("migration file %s in %s is duplicated", name, origin)
+	}
+	if !s.migrations.Append(m) {
+		return fmt.Errorf("migration file %s in %s has a duplicated version %d", name, origin, m.Version)
+	}
+	s.files[name] = data
+	return nil
+}
+
+// Open is part of source.Driver interface implementation.
+// MemorySource can only be created with the constructors of this package.
+func (s *MemorySource) Open(url string) (source.Driver, error) {
+	return nil, fmt.Errorf("open %s: %s source can't be opened with a URL", url, s.name)
+}
+
+// Close is part of source.Driver interface implementation.
+func (s *MemorySource) Close() error {
+	return nil
+}
+
+// First is part of source.Driver interface implementation.
+func (s *MemorySource) First() (uint, error) {
+	if version, ok := s.migrations.First(); ok {
+		return version, nil
+	}
+	return 0, &fs.PathError{Op: "first", Path: s.name, Err: fs.ErrNotExist}
+}
+
+// Prev is part of source.Driver interface implementation.
+func (s *MemorySource) Prev(version uint) (uint, error) {
+	if prev, ok := s.migrations.Prev(version); ok {
+		return prev, nil
+	}
+	return 0, &fs.PathError{Op: "prev for version " + strconv.FormatUint(uint64(version), 10), Path: s.name, Err: fs.ErrNotExist}
+}
+
+// Next is part of source.Driver interface implementation.
+func (s *MemorySource) Next(version uint) (uint, error) {
+	if next, ok := s.migrations.Next(version); ok {
+		return next, nil
+	}
+	return 0, &fs.PathError{Op: "next for version " + strconv.FormatUint(uint64(version), 10), Path: s.name, Err: fs.ErrNotExist}
+}
+
+// ReadUp is part of source.Driver interface implementation.
+func (s *MemorySource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations.Up(version); ok {
+		return io.NopCloser(bytes.NewReader(s.files[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: "read up for version " + strconv.FormatUint(uint64(version), 10), Path: s.name, Err: fs.ErrNotExist}
+}
+
+// ReadDown is part of source.Driver interface implementation.
+func (s *MemorySource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations.Down(version); ok {
+		return io.NopCloser(bytes.NewReader(s.files[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: "read down for version " + strconv.FormatUint(uint64(version), 10), Path: s.name, Err: fs.ErrNotExist}
+}
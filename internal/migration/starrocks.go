@@ -0,0 +1,319 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-migrate/migrate/v4/database"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+// DefaultMigrationsTable is the table with the version of the schema in the database
+const DefaultMigrationsTable = "schema_migrations"
+
+// Locker keeps the migrations of a database from running more than once at the same time
+type Locker interface {
+	// Lock returns an error wrapping database.ErrLocked if the lock is held by another
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+// StarRocksConfig is the config of the StarRocks driver
+type StarRocksConfig struct {
+	// Database to migrate, qualified with the catalog if any, e.g. iceberg.sales
+	DatabaseName string
+
+	// Default to DefaultMigrationsTable
+	MigrationsTable string
+
+	// Doris uses a unique key table with merge-on-write for the version. Default to StarRocks.
+	Flavor mysqlv1alpha1.Flavor
+
+	// Without a Locker, only the migrations with this driver are kept from running at the same time
+	Locker Locker
+}
+
+// StarRocks is a golang-migrate database driver for StarRocks and Doris,
+// which support neither GET_LOCK nor the schema_migrations table of the
+// MySQL driver. The version is kept in one row of a primary key table, so
+// that it is replaced by an INSERT at once, and the lock is held by the Locker.
+type StarRocks struct {
+	conn     *sql.Conn
+	config   StarRocksConfig
+	isLocked atomic.Bool
+}
+
+var _ database.Driver = &StarRocks{}
+
+// WithStarRocksInstance returns the driver on a connection of the cluster-level
+// client, switched to the database, and creates the migrations table if not exists.
+// Close closes the connection without closing the client. The connection isn't
+// returned to the pool of the client, as the migrations can change its session,
+// e.g. with USE, SET or SET CATALOG.
+func WithStarRocksInstance(ctx context.Context, db *sql.DB, config *StarRocksConfig) (database.Driver, error) {
+	if config == nil || config.DatabaseName == "" {
+		return nil, errors.New("no database name")
+	}
+	c := *config
+	if c.MigrationsTable == "" {
+		c.MigrationsTable = DefaultMigrationsTable
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d := &StarRocks{conn: conn, config: c}
+	if _, err := conn.ExecContext(ctx, "USE "+quoteQualifiedName(c.DatabaseName)); err != nil {
+		d.Close()
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, d.createMigrationsTableStatement()); err != nil {
+		d.Close()
+		return nil, &database.Error{OrigErr: err, Err: "failed to create migrations table", Query: []byte(d.createMigrationsTableStatement())}
+	}
+	return d, nil
+}
+
+// createMigrationsTableStatement returns CREATE TABLE of the migrations table
+// with a single row whose id is 1. The number of replicas is the default of
+// the database, e.g. replication_num in the properties of the MySQLDB.
+func (d *StarRocks) createMigrationsTableStatement() string {
+	statement := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (`id` TINYINT NOT NULL, `version` BIGINT NOT NULL, `dirty` BOOLEAN NOT NULL) ", quoteIdentifier(d.config.MigrationsTable))
+	if d.config.Flavor == mysqlv1alpha1.FlavorDoris {
+		return statement + "UNIQUE KEY(`id`) DISTRIBUTED BY HASH(`id`) BUCKETS 1 PROPERTIES (\"enable_unique_key_merge_on_write\" = \"true\")"
+	}
+	return statement + "PRIMARY KEY(`id`) DISTRIBUTED BY HASH(`id`) BUCKETS 1"
+}
+
+// Open is part of database.Driver interface implementation.
+// StarRocks can only be created with WithStarRocksInstance.
+func (d *StarRocks) Open(url string) (database.Driver, error) {
+	return nil, fmt.Errorf("open %s: StarRocks driver can't be opened with a URL", url)
+}
+
+// Close is part of database.Driver interface implementation.
+// It closes the connection instead of returning it to the pool.
+func (d *StarRocks) Close() error {
+	err := d.conn.Raw(func(any) error { return driver.ErrBadConn })
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+	return err
+}
+
+// Lock is part of database.Driver interface implementation.
+func (d *StarRocks) Lock() error {
+	if !d.isLocked.CompareAndSwap(false, true) {
+		return database.ErrLocked
+	}
+	if d.config.Locker == nil {
+		return nil
+	}
+	if err := d.config.Locker.Lock(context.Background()); err != nil {
+		d.isLocked.Store(false)
+		return err
+	}
+	return nil
+}
+
+// Unlock is part of database.Driver interface implementation.
+func (d *StarRocks) Unlock() error {
+	if !d.isLocked.Load() {
+		return database.ErrNotLocked
+	}
+	defer d.isLocked.Store(false)
+	if d.config.Locker == nil {
+		return nil
+	}
+	return d.config.Locker.Unlock(context.Background())
+}
+
+// Run is part of database.Driver interface implementation.
+// The statements in the migration run one by one, as StarRocks and Doris
+// don't run more than one statement at once.
+func (d *StarRocks) Run(migration io.Reader) error {
+	data, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	for _, statement := range splitStatements(string(data)) {
+		if _, err := d.conn.ExecContext(context.Background(), statement); err != nil {
+			return database.Error{OrigErr: err, Err: "migration failed", Query: []byte(statement)}
+		}
+	}
+	return nil
+}
+
+// SetVersion is part of database.Driver interface implementation.
+// NilVersion is kept as -1 so that the row is always replaced at once.
+func (d *StarRocks) SetVersion(version int, dirty bool) error {
+	query := fmt.Sprintf("INSERT INTO %s (`id`, `version`, `dirty`) VALUES (1, %d, %t)", quoteIdentifier(d.config.MigrationsTable), version, dirty)
+	if _, err := d.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// Version is part of database.Driver interface implementation.
+func (d *StarRocks) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+	query := fmt.Sprintf("SELECT `version`, `dirty` FROM %s WHERE `id` = 1", quoteIdentifier(d.config.MigrationsTable))
+	err := d.conn.QueryRowContext(context.Background(), query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.NilVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return version, dirty, nil
+}
+
+// Drop is part of database.Driver interface implementation.
+// It drops all the tables and views in the database, including the migrations table.
+func (d *StarRocks) Drop() error {
+	ctx := context.Background()
+	rows, err := d.conn.QueryContext(ctx, "SHOW FULL TABLES")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) < 2 {
+		return fmt.Errorf("unexpected columns of SHOW FULL TABLES: %v", columns)
+	}
+	var statements []string
+	for rows.Next() {
+		// Table name and type, followed by other columns in some versions
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		kind := "TABLE"
+		if values[1].String == "VIEW" {
+			kind = "VIEW"
+		}
+		statements = append(statements, fmt.Sprintf("DROP %s IF EXISTS %s", kind, quoteIdentifier(values[0].String)))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	for _, statement := range statements {
+		if _, err := d.conn.ExecContext(ctx, statement); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(statement)}
+		}
+	}
+	return nil
+}
+
+// splitStatements splits the migration into statements at the semicolons
+// outside quotes and comments. Statements with only comments are dropped.
+func splitStatements(migration string) []string {
+	var statements []string
+	var statement strings.Builder
+	hasCode := false
+	for i := 0; i < len(migration); {
+		end := i + 1
+		switch c := migration[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end = closingQuote(migration, i)
+			hasCode = true
+		case c == '#' || isDashComment(migration[i:]):
+			if end = strings.IndexByte(migration[i:], '\n'); end < 0 {
+				end = len(migration)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(migration[i:], "/*"):
+			// Kept as it may be a hint, e.g. /*+ SET_VAR(query_timeout = 600) */
+			if end = strings.Index(migration[i+2:], "*/"); end < 0 {
+				end = len(migration)
+			} else {
+				end += i + 4
+			}
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(statement.String()))
+			}
+			statement.Reset()
+			hasCode = false
+			i = end
+			continue
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+		statement.WriteString(migration[i:end])
+		i = end
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(statement.String()))
+	}
+	return statements
+}
+
+// closingQuote returns the index after the quote closing the one at start.
+// A quote is escaped by a backslash, except in backticks, or by doubling it.
+func closingQuote(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// isDashComment returns true if s starts with a comment of "-- ", which needs
+// a whitespace after the dashes
+func isDashComment(s string) bool {
+	return strings.HasPrefix(s, "--") && (len(s) == 2 || strings.ContainsRune(" \t\r\n", rune(s[2])))
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteQualifiedName quotes each part of a name qualified with the catalog, e.g. iceberg.sales
+func quoteQualifiedName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
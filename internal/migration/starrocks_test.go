@@ -0,0 +1,252 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	dt "github.com/golang-migrate/migrate/v4/database/testing"
+	corev1 "k8s.io/api/core/v1"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := map[string]struct {
+		migration string
+		want      []string
+	}{
+		"one statement without a semicolon": {
+			migration: "CREATE TABLE t (id INT)",
+			want:      []string{"CREATE TABLE t (id INT)"},
+		},
+		"statements on lines": {
+			migration: "CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);\n",
+			want:      []string{"CREATE TABLE t (id INT)", "INSERT INTO t VALUES (1)"},
+		},
+		"semicolons in quotes": {
+			migration: "INSERT INTO t VALUES ('a;b', \"c;\\\"d\", 'it''s;');SELECT `x;y` FROM t",
+			want:      []string{"INSERT INTO t VALUES ('a;b', \"c;\\\"d\", 'it''s;')", "SELECT `x;y` FROM t"},
+		},
+		"comments": {
+			migration: "-- create t; and u\nCREATE TABLE t (id INT); # done;\n/* u; */ CREATE TABLE u (id INT);\n-- end",
+			want:      []string{"-- create t; and u\nCREATE TABLE t (id INT)", "# done;\n/* u; */ CREATE TABLE u (id INT)"},
+		},
+		"hint": {
+			migration: "INSERT /*+ SET_VAR(query_timeout = 600) */ INTO t SELECT * FROM u;",
+			want:      []string{"INSERT /*+ SET_VAR(query_timeout = 600) */ INTO t SELECT * FROM u"},
+		},
+		"double dashes without a space": {
+			migration: "SELECT 1--1;",
+			want:      []string{"SELECT 1--1"},
+		},
+		"only comments": {
+			migration: "-- nothing to do;\n;\n",
+			want:      nil,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := splitStatements(tt.migration); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMigrationsTableStatement(t *testing.T) {
+	starRocks := &StarRocks{config: StarRocksConfig{MigrationsTable: DefaultMigrationsTable}}
+	want := "CREATE TABLE IF NOT EXISTS `schema_migrations` (`id` TINYINT NOT NULL, `version` BIGINT NOT NULL, `dirty` BOOLEAN NOT NULL) PRIMARY KEY(`id`) DISTRIBUTED BY HASH(`id`) BUCKETS 1"
+	if got := starRocks.createMigrationsTableStatement(); got != want {
+		t.Errorf("createMigrationsTableStatement() = %s, want %s", got, want)
+	}
+
+	doris := &StarRocks{config: StarRocksConfig{MigrationsTable: DefaultMigrationsTable, Flavor: mysqlv1alpha1.FlavorDoris}}
+	want = "CREATE TABLE IF NOT EXISTS `schema_migrations` (`id` TINYINT NOT NULL, `version` BIGINT NOT NULL, `dirty` BOOLEAN NOT NULL) UNIQUE KEY(`id`) DISTRIBUTED BY HASH(`id`) BUCKETS 1 PROPERTIES (\"enable_unique_key_merge_on_write\" = \"true\")"
+	if got := doris.createMigrationsTableStatement(); got != want {
+		t.Errorf("createMigrationsTableStatement() = %s, want %s", got, want)
+	}
+
+	if got := quoteQualifiedName("iceberg.sa`les"); got != "`iceberg`.`sa``les`" {
+		t.Errorf("quoteQualifiedName() = %s", got)
+	}
+}
+
+type fakeLocker struct {
+	err    error
+	locked bool
+}
+
+func (l *fakeLocker) Lock(ctx context.Context) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.locked = true
+	return nil
+}
+
+func (l *fakeLocker) Unlock(ctx context.Context) error {
+	l.locked = false
+	return nil
+}
+
+func TestStarRocksLock(t *testing.T) {
+	locker := &fakeLocker{}
+	d := &StarRocks{config: StarRocksConfig{Locker: locker}}
+	if err := d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if !locker.locked {
+		t.Error("locker is not locked")
+	}
+	if err := d.Lock(); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("Lock() error = %v, want ErrLocked", err)
+	}
+	if err := d.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if locker.locked {
+		t.Error("locker is locked after Unlock")
+	}
+	if err := d.Unlock(); !errors.Is(err, database.ErrNotLocked) {
+		t.Errorf("Unlock() error = %v, want ErrNotLocked", err)
+	}
+
+	// The driver stays unlocked if the Locker fails
+	locker.err = database.ErrLocked
+	if err := d.Lock(); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("Lock() error = %v, want ErrLocked", err)
+	}
+	locker.err = nil
+	if err := d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sessionDriver counts the connections opened and closed, as the session of a
+// connection used by migrations must not be reused
+type sessionDriver struct {
+	opened, closed int
+}
+
+func (d *sessionDriver) Open(string) (driver.Conn, error) {
+	d.opened++
+	return &sessionConn{driver: d}, nil
+}
+
+type sessionConn struct {
+	driver *sessionDriver
+}
+
+func (c *sessionConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *sessionConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c *sessionConn) Close() error {
+	c.driver.closed++
+	return nil
+}
+func (c *sessionConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func TestStarRocksClose(t *testing.T) {
+	sessions := &sessionDriver{}
+	sql.Register("sessiondriver", sessions)
+	db, err := sql.Open("sessiondriver", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	d, err := WithStarRocksInstance(ctx, db, &StarRocksConfig{DatabaseName: "sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(strings.NewReader("SET CATALOG hive;")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.closed != 1 {
+		t.Errorf("closed connections = %d, want 1", sessions.closed)
+	}
+
+	// The next statement of the client runs on a new connection
+	if _, err := db.ExecContext(ctx, "CREATE USER 'app'@'%'"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.opened != 2 {
+		t.Errorf("opened connections = %d, want 2", sessions.opened)
+	}
+}
+
+// TestStarRocks runs against a StarRocks or Doris at STARROCKS_DSN if it is set, e.g. with
+//
+//	docker run -d -p 9030:9030 -p 8030:8030 -p 8040:8040 starrocks/allin1-ubuntu
+//	STARROCKS_DSN='root@tcp(localhost:9030)/' go test ./internal/migration -run StarRocks
+func TestStarRocks(t *testing.T) {
+	dsn := os.Getenv("STARROCKS_DSN")
+	if dsn == "" {
+		t.Skip("STARROCKS_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "DROP DATABASE IF EXISTS migrate_test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE DATABASE migrate_test PROPERTIES (\"replication_num\" = \"1\")"); err != nil {
+		t.Fatal(err)
+	}
+	config := &StarRocksConfig{DatabaseName: "migrate_test", Flavor: mysqlv1alpha1.Flavor(os.Getenv("STARROCKS_FLAVOR"))}
+
+	d, err := WithStarRocksInstance(ctx, db, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt.Test(t, d, []byte("CREATE TABLE t (id INT) DUPLICATE KEY(id) DISTRIBUTED BY HASH(id) BUCKETS 1;\nSELECT 1;"))
+
+	source, err := NewConfigMapSource([]corev1.ConfigMap{{Data: map[string]string{
+		"1_create.up.sql":   "CREATE TABLE t (id INT) DUPLICATE KEY(id) DISTRIBUTED BY HASH(id) BUCKETS 1;\nINSERT INTO t VALUES (1);",
+		"1_create.down.sql": "DROP TABLE t;",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err = WithStarRocksInstance(ctx, db, config); err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithInstance(ConfigMapSourceName, source, "migrate_test", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt.TestMigrate(t, m)
+}
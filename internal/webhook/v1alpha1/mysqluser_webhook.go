@@ -0,0 +1,294 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var mysqluserlog = logf.Log.WithName("mysqluser-resource")
+
+var (
+	identifierRegexp = regexp.MustCompile(`^[A-Za-z0-9_$-]+$`)
+	// Function names may have the argument types, e.g. my_udf(INT, VARCHAR)
+	functionNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_$]+(\([A-Za-z0-9_, ()]*\))?$`)
+)
+
+// SetupMySQLUserWebhookWithManager registers the webhook for MySQLUser in the manager.
+func SetupMySQLUserWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&mysqlv1alpha1.MySQLUser{}).
+		WithValidator(&MySQLUserCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-mysql-nakamasato-com-v1alpha1-mysqluser,mutating=false,failurePolicy=fail,sideEffects=None,groups=mysql.nakamasato.com,resources=mysqlusers,verbs=create;update,versions=v1alpha1,name=vmysqluser-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MySQLUserCustomValidator validates the grants of MySQLUser against the
+// flavor of the referenced MySQL when it is created or updated.
+type MySQLUserCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &MySQLUserCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type MySQLUser.
+func (v *MySQLUserCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	mysqlUser, ok := obj.(*mysqlv1alpha1.MySQLUser)
+	if !ok {
+		return nil, fmt.Errorf("expected a MySQLUser object but got %T", obj)
+	}
+	mysqluserlog.Info("Validation for MySQLUser upon creation", "name", mysqlUser.GetName())
+	return v.validate(ctx, mysqlUser)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type MySQLUser.
+func (v *MySQLUserCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	mysqlUser, ok := newObj.(*mysqlv1alpha1.MySQLUser)
+	if !ok {
+		return nil, fmt.Errorf("expected a MySQLUser object for the newObj but got %T", newObj)
+	}
+	mysqluserlog.Info("Validation for MySQLUser upon update", "name", mysqlUser.GetName())
+	return v.validate(ctx, mysqlUser)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type MySQLUser.
+func (v *MySQLUserCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *MySQLUserCustomValidator) validate(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser) (admission.Warnings, error) {
+	flavor, err := v.flavor(ctx, mysqlUser)
+	if err != nil {
+		return nil, err
+	}
+	errs := ValidateGrants(field.NewPath("spec", "grants"), mysqlUser.Spec.Grants, flavor)
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return nil, apierrors.NewInvalid(mysqlv1alpha1.GroupVersion.WithKind("MySQLUser").GroupKind(), mysqlUser.Name, errs)
+}
+
+// flavor returns the flavor of the referenced MySQL, or empty if the MySQL
+// doesn't exist yet or has no flavor.
+func (v *MySQLUserCustomValidator) flavor(ctx context.Context, mysqlUser *mysqlv1alpha1.MySQLUser) (mysqlv1alpha1.Flavor, error) {
+	if v.Client == nil {
+		return "", nil
+	}
+	mysql := &mysqlv1alpha1.MySQL{}
+	err := v.Client.Get(ctx, types.NamespacedName{Namespace: mysqlUser.Namespace, Name: mysqlUser.Spec.ClusterName}, mysql)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return mysql.Spec.Flavor, nil
+}
+
+// ValidateGrants checks the objects and privileges of the grants, and
+// rejects grants that conflict with another grant on the same object.
+// The controller runs it on the grants of MySQLGrants and expanded
+// MySQLGrantTemplates, which don't pass this webhook.
+func ValidateGrants(path *field.Path, grants []mysqlv1alpha1.Grant, flavor mysqlv1alpha1.Flavor) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, grant := range grants {
+		errs = append(errs, validateObject(path.Index(i).Child("object"), grant.Object, flavor)...)
+		errs = append(errs, validatePrivileges(path.Index(i), grant, flavor)...)
+		errs = append(errs, validateColumns(path.Index(i).Child("columns"), grant)...)
+	}
+	return append(errs, validateDuplicates(path, grants)...)
+}
+
+func validateObject(path *field.Path, object mysqlv1alpha1.ObjectRef, flavor mysqlv1alpha1.Flavor) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(allowedPrivileges(flavor, object.Kind)) == 0 {
+		return append(errs, field.NotSupported(path.Child("kind"), object.Kind, supportedKinds(flavor)))
+	}
+
+	switch object.Kind {
+	case mysqlv1alpha1.ObjectKindSystem:
+		if object.Catalog != "" || object.Database != "" || object.Name != "" {
+			errs = append(errs, field.Invalid(path, object, "SYSTEM has no catalog, database or name"))
+		}
+		return errs
+	case mysqlv1alpha1.ObjectKindDatabase:
+		errs = append(errs, forbidden(path.Child("database"), object.Database, object.Kind)...)
+	case mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+		if object.Database == "" {
+			errs = append(errs, field.Required(path.Child("database"), fmt.Sprintf("%s requires the database", object.Kind)))
+		} else {
+			errs = append(errs, validateName(path.Child("database"), object.Database, identifierRegexp)...)
+		}
+		if object.Database == mysqlv1alpha1.ObjectNameAll && object.Name != mysqlv1alpha1.ObjectNameAll {
+			errs = append(errs, field.Invalid(path.Child("name"), object.Name, "must be \"*\" if the database is \"*\""))
+		}
+	case mysqlv1alpha1.ObjectKindFunction:
+		if object.Database != "" {
+			errs = append(errs, validateName(path.Child("database"), object.Database, identifierRegexp)...)
+		}
+	default:
+		// CATALOG and objects that don't belong to a catalog
+		errs = append(errs, forbidden(path.Child("catalog"), object.Catalog, object.Kind)...)
+		errs = append(errs, forbidden(path.Child("database"), object.Database, object.Kind)...)
+	}
+
+	if object.Catalog != "" {
+		errs = append(errs, validateName(path.Child("catalog"), object.Catalog, identifierRegexp)...)
+	}
+	if object.Name == "" {
+		return append(errs, field.Required(path.Child("name"), fmt.Sprintf("%s requires the name", object.Kind)))
+	}
+	nameRegexp := identifierRegexp
+	if object.Kind == mysqlv1alpha1.ObjectKindFunction {
+		nameRegexp = functionNameRegexp
+	}
+	return append(errs, validateName(path.Child("name"), object.Name, nameRegexp)...)
+}
+
+func validateName(path *field.Path, name string, nameRegexp *regexp.Regexp) field.ErrorList {
+	if name == mysqlv1alpha1.ObjectNameAll || nameRegexp.MatchString(name) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(path, name, "must be \"*\" or a name without spaces, quotes, dots or semicolons")}
+}
+
+func forbidden(path *field.Path, value string, kind mysqlv1alpha1.ObjectKind) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(path, fmt.Sprintf("must be empty for %s", kind))}
+}
+
+func validatePrivileges(path *field.Path, grant mysqlv1alpha1.Grant, flavor mysqlv1alpha1.Flavor) field.ErrorList {
+	allowed := allowedPrivileges(flavor, grant.Object.Kind)
+	if len(allowed) == 0 {
+		// The kind is already reported
+		return nil
+	}
+	errs := field.ErrorList{}
+	if len(grant.Privileges) == 0 {
+		errs = append(errs, field.Required(path.Child("privileges"), "at least one privilege is required"))
+	}
+	for j, priv := range grant.Privileges {
+		if !slices.Contains(allowed, normalizePrivilege(priv)) {
+			errs = append(errs, field.NotSupported(path.Child("privileges").Index(j), priv, allowed))
+		}
+	}
+	return errs
+}
+
+func validateColumns(path *field.Path, grant mysqlv1alpha1.Grant) field.ErrorList {
+	if len(grant.Columns) == 0 {
+		return nil
+	}
+	switch grant.Object.Kind {
+	case mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView:
+	default:
+		return field.ErrorList{field.Forbidden(path, fmt.Sprintf("columns can't be specified for %s", grant.Object.Kind))}
+	}
+	if grant.Object.Name == mysqlv1alpha1.ObjectNameAll {
+		return field.ErrorList{field.Forbidden(path, "columns can't be specified for all objects")}
+	}
+	errs := field.ErrorList{}
+	for j, column := range grant.Columns {
+		if !identifierRegexp.MatchString(column) {
+			errs = append(errs, field.Invalid(path.Index(j), column, "must be a column name"))
+		}
+	}
+	return errs
+}
+
+// validateDuplicates rejects grants on the same object and columns with
+// the same grant option, whose privileges would override each other, and
+// privileges granted both with and without the grant option.
+func validateDuplicates(path *field.Path, grants []mysqlv1alpha1.Grant) field.ErrorList {
+	errs := field.ErrorList{}
+	seen := map[string]int{}
+	privileges := map[string]map[string]bool{}
+	for i, grant := range grants {
+		object := objectKey(grant)
+		key := fmt.Sprintf("%s|%t", object, grant.GrantOption)
+		if j, found := seen[key]; found {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("object"), fmt.Sprintf("%s is also granted in %s, merge the privileges into one grant", grant.Object, path.Index(j))))
+			continue
+		}
+		seen[key] = i
+
+		if privileges[object] == nil {
+			privileges[object] = map[string]bool{}
+		}
+		for j, priv := range grant.Privileges {
+			priv = normalizePrivilege(priv)
+			if grantOption, found := privileges[object][priv]; found && grantOption != grant.GrantOption {
+				errs = append(errs, field.Invalid(path.Index(i).Child("privileges").Index(j), priv, fmt.Sprintf("%s on %s is granted both with and without grant option", priv, grant.Object)))
+			}
+			privileges[object][priv] = grant.GrantOption
+		}
+	}
+	return errs
+}
+
+// objectKey identifies the object and columns of the grant, regarding the
+// internal catalog the same as no catalog
+func objectKey(grant mysqlv1alpha1.Grant) string {
+	object := grant.Object
+	if slices.Contains(defaultCatalogs, object.Catalog) {
+		object.Catalog = ""
+	}
+	columns := make([]string, 0, len(grant.Columns))
+	for _, column := range grant.Columns {
+		columns = append(columns, strings.ToLower(column))
+	}
+	slices.Sort(columns)
+	return fmt.Sprintf("%s|%s|%s|%s|%s", object.Kind, object.Catalog, object.Database, object.Name, strings.Join(columns, ","))
+}
+
+func normalizePrivilege(priv string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(priv)), " ")
+}
+
+func supportedKinds(flavor mysqlv1alpha1.Flavor) []string {
+	kinds := []string{}
+	for _, kind := range []mysqlv1alpha1.ObjectKind{
+		mysqlv1alpha1.ObjectKindSystem, mysqlv1alpha1.ObjectKindCatalog, mysqlv1alpha1.ObjectKindDatabase,
+		mysqlv1alpha1.ObjectKindTable, mysqlv1alpha1.ObjectKindView, mysqlv1alpha1.ObjectKindMaterializedView,
+		mysqlv1alpha1.ObjectKindFunction, mysqlv1alpha1.ObjectKindResource, mysqlv1alpha1.ObjectKindResourceGroup,
+		mysqlv1alpha1.ObjectKindStorageVolume, mysqlv1alpha1.ObjectKindWorkloadGroup,
+	} {
+		if len(allowedPrivileges(flavor, kind)) > 0 {
+			kinds = append(kinds, string(kind))
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+var _ = Describe("MySQLUser Webhook", func() {
+	var validator *MySQLUserCustomValidator
+
+	newMySQLUser := func(grants ...mysqlv1alpha1.Grant) *mysqlv1alpha1.MySQLUser {
+		return &mysqlv1alpha1.MySQLUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default"},
+			Spec:       mysqlv1alpha1.MySQLUserSpec{ClusterName: "mysql", Grants: grants},
+		}
+	}
+	table := mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1"}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(mysqlv1alpha1.AddToScheme(scheme)).To(Succeed())
+		mysql := &mysqlv1alpha1.MySQL{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "default"},
+			Spec:       mysqlv1alpha1.MySQLSpec{Flavor: mysqlv1alpha1.FlavorStarRocks},
+		}
+		validator = &MySQLUserCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(mysql).Build()}
+	})
+
+	It("Should admit valid grants", func() {
+		_, err := validator.ValidateCreate(context.TODO(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"select", "INSERT"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table, Columns: []string{"id"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"CREATE  TABLE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindDatabase, Catalog: "hive", Name: "*"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindFunction, Database: "db1", Name: "my_udf(INT, VARCHAR)"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"NODE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem}},
+		))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should reject unknown privileges for the kind and flavor", func() {
+		_, err := validator.ValidateCreate(context.TODO(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"SELET"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT_PRIV"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"USAGE_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindWorkloadGroup, Name: "normal"}},
+		))
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`spec.grants[0].privileges[0]: Unsupported value: "SELET"`))
+		Expect(err.Error()).To(ContainSubstring(`spec.grants[1].privileges[0]: Unsupported value: "SELECT_PRIV"`))
+		Expect(err.Error()).To(ContainSubstring(`spec.grants[2].object.kind: Unsupported value: "WORKLOAD GROUP"`))
+	})
+
+	It("Should accept privileges of any flavor if the MySQL has no flavor", func() {
+		validator.Client = nil
+		_, err := validator.ValidateCreate(context.TODO(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"LOAD_PRIV"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl2"}},
+		))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should reject invalid objects", func() {
+		_, err := validator.ValidateUpdate(context.TODO(), newMySQLUser(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Name: "tbl1"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "tbl1; DROP USER root"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"USAGE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindResource, Database: "db1", Name: "spark"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"NODE"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindSystem, Name: "x"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Database: "db1", Name: "*"}, Columns: []string{"id"}},
+		))
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.grants[0].object.database: Required value"))
+		Expect(err.Error()).To(ContainSubstring("spec.grants[1].object.name: Invalid value"))
+		Expect(err.Error()).To(ContainSubstring("spec.grants[2].object.database: Forbidden"))
+		Expect(err.Error()).To(ContainSubstring("spec.grants[3].object: Invalid value"))
+		Expect(err.Error()).To(ContainSubstring("spec.grants[4].columns: Forbidden"))
+	})
+
+	It("Should reject conflicting duplicate objects", func() {
+		_, err := validator.ValidateCreate(context.TODO(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"INSERT"}, Object: mysqlv1alpha1.ObjectRef{Kind: mysqlv1alpha1.ObjectKindTable, Catalog: "default_catalog", Database: "db1", Name: "tbl1"}},
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table, GrantOption: true},
+		))
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.grants[1].object: Duplicate value"))
+		Expect(err.Error()).To(ContainSubstring("spec.grants[2].privileges[0]: Invalid value"))
+
+		// Different privileges with and without grant option are allowed
+		_, err = validator.ValidateCreate(context.TODO(), newMySQLUser(
+			mysqlv1alpha1.Grant{Privileges: []string{"SELECT"}, Object: table},
+			mysqlv1alpha1.Grant{Privileges: []string{"INSERT"}, Object: table, GrantOption: true},
+		))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
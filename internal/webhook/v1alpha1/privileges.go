@@ -0,0 +1,76 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+	"sort"
+
+	mysqlv1alpha1 "github.com/nakamasato/mysql-operator/api/v1alpha1"
+)
+
+// starRocksPrivileges are the privileges that can be granted on each kind of object in StarRocks.
+// Ref: https://docs.starrocks.io/docs/administration/user_privs/privilege_item/
+var starRocksPrivileges = map[mysqlv1alpha1.ObjectKind][]string{
+	mysqlv1alpha1.ObjectKindSystem: {
+		"NODE", "GRANT", "CREATE RESOURCE GROUP", "CREATE RESOURCE", "CREATE EXTERNAL CATALOG", "PLUGIN",
+		"REPOSITORY", "BLACKLIST", "FILE", "OPERATE", "CREATE GLOBAL FUNCTION", "CREATE STORAGE VOLUME", "SECURITY",
+	},
+	mysqlv1alpha1.ObjectKindCatalog:          {"USAGE", "CREATE DATABASE", "DROP", "ALTER", "ALL"},
+	mysqlv1alpha1.ObjectKindDatabase:         {"ALTER", "DROP", "CREATE TABLE", "CREATE VIEW", "CREATE FUNCTION", "CREATE MATERIALIZED VIEW", "CREATE PIPE", "ALL"},
+	mysqlv1alpha1.ObjectKindTable:            {"ALTER", "DROP", "SELECT", "INSERT", "EXPORT", "UPDATE", "DELETE", "ALL"},
+	mysqlv1alpha1.ObjectKindView:             {"ALTER", "DROP", "SELECT", "ALL"},
+	mysqlv1alpha1.ObjectKindMaterializedView: {"SELECT", "ALTER", "REFRESH", "DROP", "ALL"},
+	mysqlv1alpha1.ObjectKindFunction:         {"USAGE", "DROP", "ALL"},
+	mysqlv1alpha1.ObjectKindResource:         {"USAGE", "ALTER", "DROP", "ALL"},
+	mysqlv1alpha1.ObjectKindResourceGroup:    {"ALTER", "DROP", "ALL"},
+	mysqlv1alpha1.ObjectKindStorageVolume:    {"USAGE", "ALTER", "DROP", "ALL"},
+}
+
+// dorisDataPrivileges are the privileges on catalogs, databases and tables in Doris.
+var dorisDataPrivileges = []string{"GRANT_PRIV", "SELECT_PRIV", "LOAD_PRIV", "ALTER_PRIV", "CREATE_PRIV", "DROP_PRIV", "SHOW_VIEW_PRIV", "ALL"}
+
+// dorisPrivileges are the privileges that can be granted on each kind of object in Doris.
+// Views and materialized views are granted as tables.
+// Ref: https://doris.apache.org/docs/admin-manual/auth/authentication-and-authorization
+var dorisPrivileges = map[mysqlv1alpha1.ObjectKind][]string{
+	mysqlv1alpha1.ObjectKindSystem:           append([]string{"NODE_PRIV", "ADMIN_PRIV"}, dorisDataPrivileges...),
+	mysqlv1alpha1.ObjectKindCatalog:          dorisDataPrivileges,
+	mysqlv1alpha1.ObjectKindDatabase:         dorisDataPrivileges,
+	mysqlv1alpha1.ObjectKindTable:            dorisDataPrivileges,
+	mysqlv1alpha1.ObjectKindView:             dorisDataPrivileges,
+	mysqlv1alpha1.ObjectKindMaterializedView: dorisDataPrivileges,
+	mysqlv1alpha1.ObjectKindResource:         {"USAGE_PRIV", "GRANT_PRIV"},
+	mysqlv1alpha1.ObjectKindWorkloadGroup:    {"USAGE_PRIV", "GRANT_PRIV"},
+}
+
+// allowedPrivileges returns the privileges that can be granted on the kind
+// of object in the flavor, or in any flavor if the flavor is not set.
+func allowedPrivileges(flavor mysqlv1alpha1.Flavor, kind mysqlv1alpha1.ObjectKind) []string {
+	switch flavor {
+	case mysqlv1alpha1.FlavorStarRocks:
+		return starRocksPrivileges[kind]
+	case mysqlv1alpha1.FlavorDoris:
+		return dorisPrivileges[kind]
+	}
+	privileges := append(append([]string{}, starRocksPrivileges[kind]...), dorisPrivileges[kind]...)
+	sort.Strings(privileges)
+	return slices.Compact(privileges)
+}
+
+// defaultCatalogs are the names of the internal catalog, which is the same as no catalog
+var defaultCatalogs = []string{"default_catalog", "internal"}